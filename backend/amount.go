@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+
+	"github.com/shopspring/decimal"
+)
+
+// Amount es un importe monetario decimal exacto que siempre se serializa con dos decimales
+// (19.9 -> 19.90, 20 -> 20.00). Se lee y se escribe en la columna NUMERIC a través del
+// Scan/Value de decimal.Decimal, sin pasar nunca por float64.
+type Amount struct {
+	decimal.Decimal
+}
+
+// newAmount construye un importe a partir de su representación decimal ("19.90")
+func newAmount(s string) (Amount, error) {
+	d, err := decimal.NewFromString(s)
+	return Amount{d}, err
+}
+
+// Add devuelve a + b
+func (a Amount) Add(b Amount) Amount {
+	return Amount{a.Decimal.Add(b.Decimal)}
+}
+
+// Sub devuelve a - b
+func (a Amount) Sub(b Amount) Amount {
+	return Amount{a.Decimal.Sub(b.Decimal)}
+}
+
+// Neg devuelve -a
+func (a Amount) Neg() Amount {
+	return Amount{a.Decimal.Neg()}
+}
+
+// Si es true los importes se serializan como cadena ("19.90") en lugar de número (AMOUNT_AS_STRING)
+var amountAsString = false
+
+// Si es true solo se aceptan importes en notación decimal simple: "1e3" se rechaza
+// aunque sea un número JSON válido (STRICT_AMOUNT_NUMBERS)
+var strictAmountNumbers = false
+
+// Si es true se admiten importes 0 (marcadores, artículos gratis) al crear y actualizar
+// (ALLOW_ZERO_AMOUNT). Los negativos se siguen rechazando. La tabla no tiene un CHECK
+// sobre amount; si se añadiera uno como amount > 0 habría que relajarlo a amount >= 0.
+var allowZeroAmount = false
+
+// validAmount indica si el importe de una transacción es aceptable: positivo, o también 0
+// con ALLOW_ZERO_AMOUNT
+func validAmount(a Amount) bool {
+	if allowZeroAmount {
+		return !a.IsNegative()
+	}
+	return a.IsPositive()
+}
+
+// Mayor importe que cabe en la columna amount, NUMERIC(10, 2). Por encima PostgreSQL
+// responde con un error de desbordamiento que llegaría al cliente como un 500.
+var maxAmount = Amount{decimal.RequireFromString("99999999.99")}
+
+// amountTooLarge indica si el importe no cabe en la columna. Se compara ya redondeado a
+// céntimos, como lo guarda PostgreSQL: 99999999.995 también desborda.
+func amountTooLarge(a Amount) bool {
+	return a.Abs().Round(2).GreaterThan(maxAmount.Decimal)
+}
+
+// Forma decimal aceptada en modo estricto: signo opcional, dígitos y decimales opcionales
+// ("+500" solo puede llegar como cadena, porque JSON no admite el signo + en números)
+var plainDecimal = regexp.MustCompile(`^[-+]?[0-9]+(\.[0-9]+)?$`)
+
+func (a Amount) String() string {
+	return a.StringFixed(2)
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	if amountAsString {
+		return json.Marshal(a.String())
+	}
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON acepta el importe como número o como cadena, para que los clientes
+// puedan reenviar lo que recibieron con AMOUNT_AS_STRING activo.
+// Trabaja sobre el token sin procesar, así que en modo estricto puede distinguir
+// "1000" de "1e3" antes de convertirlo a número.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		data = []byte(s)
+	}
+	if strictAmountNumbers && !plainDecimal.Match(data) {
+		return newAPIError(msgAmountNotDecimal, data)
+	}
+	parsed, err := newAmount(string(data))
+	if err != nil {
+		return newAPIError(msgInvalidAmount, data)
+	}
+	*a = parsed
+	return nil
+}
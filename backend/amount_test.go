@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAmountMarshalJSONTwoDecimals(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"19.9", "19.90"},
+		{"20", "20.00"},
+		{"0.5", "0.50"},
+		{"19.99", "19.99"},
+	}
+	for _, c := range cases {
+		got, err := json.Marshal(mustAmount(t, c.in))
+		if err != nil {
+			t.Fatalf("Marshal(%s): %v", c.in, err)
+		}
+		if string(got) != c.want {
+			t.Errorf("Marshal(%s) = %s, se esperaba %s", c.in, got, c.want)
+		}
+	}
+}
+
+func TestAmountMarshalJSONAsString(t *testing.T) {
+	amountAsString = true
+	defer func() { amountAsString = false }()
+
+	got, err := json.Marshal(mustAmount(t, "19.9"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `"19.90"` {
+		t.Errorf(`Marshal(19.9) = %s, se esperaba "19.90"`, got)
+	}
+}
+
+// mustAmount construye un Amount o detiene el test
+func mustAmount(t *testing.T, s string) Amount {
+	t.Helper()
+	a, err := newAmount(s)
+	if err != nil {
+		t.Fatalf("newAmount(%q): %v", s, err)
+	}
+	return a
+}
+
+func TestAmountUnmarshalStrictNumbers(t *testing.T) {
+	cases := []struct {
+		body   string
+		strict bool
+		want   string // "" si se espera un error
+	}{
+		{"19.99", false, "19.99"},
+		{"19.99", true, "19.99"},
+		{`"19.99"`, true, "19.99"},
+		{"1e3", false, "1000.00"},
+		{"1e3", true, ""},
+		{`"1e3"`, true, ""},
+	}
+	defer func() { strictAmountNumbers = false }()
+	for _, c := range cases {
+		strictAmountNumbers = c.strict
+		var a Amount
+		err := json.Unmarshal([]byte(c.body), &a)
+		switch {
+		case c.want == "" && err == nil:
+			t.Errorf("strict=%t %s: se esperaba un error y se obtuvo %s", c.strict, c.body, a)
+		case c.want != "" && err != nil:
+			t.Errorf("strict=%t %s: error inesperado: %v", c.strict, c.body, err)
+		case c.want != "" && a.String() != c.want:
+			t.Errorf("strict=%t %s = %s, se esperaba %s", c.strict, c.body, a, c.want)
+		}
+	}
+}
+
+func TestValidAmountAllowZero(t *testing.T) {
+	defer func() { allowZeroAmount = false }()
+
+	cases := []struct {
+		amount    string
+		allowZero bool
+		want      bool
+	}{
+		{"0", false, false},
+		{"0", true, true},
+		{"0.01", false, true},
+		{"0.01", true, true},
+		{"-0.01", false, false},
+		{"-0.01", true, false},
+	}
+	for _, c := range cases {
+		allowZeroAmount = c.allowZero
+		if got := validAmount(mustAmount(t, c.amount)); got != c.want {
+			t.Errorf("validAmount(%s) con ALLOW_ZERO_AMOUNT=%t = %t, se esperaba %t", c.amount, c.allowZero, got, c.want)
+		}
+	}
+}
+
+func TestAmountTooLargeBoundary(t *testing.T) {
+	cases := []struct {
+		amount string
+		want   bool
+	}{
+		{"99999999.99", false},
+		{"-99999999.99", false},
+		{"99999999.994", false}, // se guarda redondeado como 99999999.99
+		{"99999999.995", true},  // redondeado serían 100000000.00
+		{"100000000.00", true},
+		{"-100000000.00", true},
+	}
+	for _, c := range cases {
+		if got := amountTooLarge(mustAmount(t, c.amount)); got != c.want {
+			t.Errorf("amountTooLarge(%s) = %t, se esperaba %t", c.amount, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Heurísticas de /transactions/anomalies, configurables por entorno:
+//   - ANOMALY_EXPENSE_KEYWORDS: palabras que delatan un gasto; un ingreso cuya descripción
+//     contenga alguna se marca como "expense_keyword" (posible gasto guardado como ingreso)
+//   - ANOMALY_STDDEV: desviaciones típicas respecto a la media del mismo beneficiario y tipo
+//     a partir de las cuales el importe se marca como "amount_outlier"
+//   - ANOMALY_MIN_HISTORY: transacciones previas del beneficiario necesarias para juzgar
+//     si un importe es atípico
+var (
+	anomalyExpenseKeywords = []string{
+		"compra", "supermercado", "restaurante", "alquiler", "factura", "gasolina", "recibo",
+		"grocery", "restaurant", "rent", "bill", "fuel", "purchase",
+	}
+	anomalyStdDev     = 3
+	anomalyMinHistory = 5
+)
+
+// Máximo de filas devueltas por /transactions/anomalies
+const maxAnomalies = 200
+
+// Anomaly es una transacción sospechosa con los motivos por los que se marcó
+type Anomaly struct {
+	Transaction Transaction     `json:"transaction"`
+	Reasons     []AnomalyReason `json:"reasons"`
+}
+
+// AnomalyReason explica un motivo: un código estable para la interfaz y un texto legible
+type AnomalyReason struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Handler para /transactions/anomalies (GET: transacciones que parecen erróneas según las
+// heurísticas configuradas, con el motivo de cada una). Acepta los filtros de la lista.
+func getTransactionAnomalies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r, "GET")
+		return
+	}
+
+	filter, err := buildTransactionFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return
+	}
+	patterns := make([]string, len(anomalyExpenseKeywords))
+	for i, kw := range anomalyExpenseKeywords {
+		patterns[i] = "%" + escapeLike(kw) + "%"
+	}
+	keyword := "(type = 'income' AND description ILIKE ANY(" + filter.arg(pq.Array(patterns)) + "))"
+	outlier := "(s.n >= " + filter.arg(anomalyMinHistory) + " AND s.sd > 0 AND abs(" + magnitudeSQL() + " - s.avg) > " +
+		filter.arg(anomalyStdDev) + " * s.sd)"
+
+	// Las columnas de stats tienen nombres propios para no chocar con las de transactions
+	rows, err := queryReadDB(r.Context(), "transaction_anomalies", `
+		WITH stats AS (
+			SELECT lower(payee) AS p, type AS stype, AVG(`+magnitudeSQL()+`) AS avg, STDDEV_SAMP(`+magnitudeSQL()+`) AS sd, COUNT(*) AS n
+			FROM transactions WHERE `+visibleTransactions+` AND payee IS NOT NULL
+			GROUP BY 1, 2
+		)
+		SELECT `+transactionColumns+`, `+keyword+`, COALESCE(`+outlier+`, false), COALESCE(s.avg, 0)
+		FROM transactions LEFT JOIN stats s ON lower(payee) = s.p AND type = s.stype`+
+		filter.where()+` AND (`+keyword+` OR COALESCE(`+outlier+`, false))
+		ORDER BY created_at DESC LIMIT `+filter.arg(maxAnomalies), filter.args...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	anomalies := []Anomaly{}
+	for rows.Next() {
+		var t Transaction
+		var isKeyword, isOutlier bool
+		var avg Amount
+		if err := rows.Scan(append(transactionScanDest(&t), &isKeyword, &isOutlier, &avg)...); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		apiAmount(&t)
+		a := Anomaly{Transaction: t, Reasons: []AnomalyReason{}}
+		if isKeyword {
+			a.Reasons = append(a.Reasons, AnomalyReason{"expense_keyword", localize(r, msgAnomalyExpenseKeyword)})
+		}
+		if isOutlier {
+			a.Reasons = append(a.Reasons, AnomalyReason{"amount_outlier", localize(r, msgAnomalyOutlier, avg)})
+		}
+		anomalies = append(anomalies, a)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, anomalies)
+}
+
+// loadAnomalyConfig lee la configuración de las heurísticas de anomalías
+func loadAnomalyConfig() {
+	if keywords := splitList(strings.ToLower(os.Getenv("ANOMALY_EXPENSE_KEYWORDS"))); len(keywords) > 0 {
+		anomalyExpenseKeywords = keywords
+	}
+	anomalyStdDev = positiveIntEnv("ANOMALY_STDDEV", anomalyStdDev)
+	anomalyMinHistory = positiveIntEnv("ANOMALY_MIN_HISTORY", anomalyMinHistory)
+}
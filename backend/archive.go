@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Número de transacciones a partir del cual la lista avisa con X-Data-Warning (DATA_WARNING_THRESHOLD)
+var dataWarningThreshold = 100000
+
+// Antigüedad por defecto (en días) de lo que archiva POST /transactions/archive sin ?before= (ARCHIVE_AGE_DAYS)
+var archiveAgeDays = 365
+
+// archiveTreeSQL selecciona lo que se archiva: las transacciones de primer nivel (sin parent_id)
+// anteriores al corte y, con ellas, todas sus partes sea cual sea su fecha. Una división se
+// archiva entera o no se archiva: si la original se moviera sola, sus partes quedarían en la
+// tabla principal con parent_id a NULL y contarían como transacciones sueltas.
+const archiveTreeSQL = `
+	WITH RECURSIVE tree AS (
+		SELECT id FROM transactions
+		WHERE parent_id IS NULL AND created_at < ($1::date)::timestamp AT TIME ZONE $2 AND NOT is_template
+		UNION ALL
+		SELECT t.id FROM transactions t JOIN tree ON t.parent_id = tree.id
+	)`
+
+// Handler para /transactions/archive (POST: mover al archivo las transacciones anteriores a ?before=,
+// un día que empieza a medianoche en APP_TIMEZONE, como en los demás filtros de fecha). Las
+// partes de una división se archivan junto con la original, según la fecha de esta.
+// La copia a transactions_archive y el borrado de la tabla principal ocurren en una única
+// transacción de base de datos: o se mueven todas las filas o ninguna.
+func archiveTransactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		methodNotAllowed(w, r, "POST")
+		return
+	}
+
+	before, err := parseDateParam(r.URL.Query(), "before")
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return
+	}
+	if before.IsZero() {
+		now := appNow()
+		before = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, appLocation).AddDate(0, 0, -archiveAgeDays)
+	}
+
+	ctx := r.Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	cutoff := before.Format(dateLayout)
+	res, err := execTx(ctx, tx, "archive_copy",
+		archiveTreeSQL+" INSERT INTO transactions_archive ("+transactionColumns+", "+importColumns+") SELECT "+transactionColumns+", "+importColumns+
+			" FROM transactions WHERE id IN (SELECT id FROM tree)", cutoff, appLocation.String())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	copied, err := res.RowsAffected()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res, err = execTx(ctx, tx, "archive_delete",
+		archiveTreeSQL+" DELETE FROM transactions WHERE id IN (SELECT id FROM tree)", cutoff, appLocation.String())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Con el bloqueo de la transacción ambos conjuntos deberían coincidir; si no, no se confirma nada
+	if copied != deleted {
+		http.Error(w, localize(r, msgArchiveMismatch, copied, deleted), http.StatusConflict)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]any{"archived": copied, "before": cutoff})
+}
+
+// setDataWarning añade X-Data-Warning a la respuesta si la tabla principal supera el umbral,
+// sugiriendo archivar (el texto va en una cabecera: sin acentos). Es solo informativo:
+// el cuerpo de la respuesta no cambia.
+func setDataWarning(w http.ResponseWriter, r *http.Request) error {
+	total, err := estimateTransactionCount(r.Context())
+	if err != nil {
+		return err
+	}
+	if total > dataWarningThreshold {
+		w.Header().Set("X-Data-Warning", localize(r, msgDataWarning, total, dataWarningThreshold))
+	}
+	return nil
+}
+
+// estimateTransactionCount devuelve el número aproximado de filas de la tabla principal
+// (sin el archivo) según las estadísticas de PostgreSQL. Se consulta en cada lista, así que
+// no puede ser un COUNT(*) que recorra la tabla entera; para un aviso basta la estimación
+// que mantienen VACUUM y ANALYZE. Una tabla nunca analizada (reltuples -1) cuenta como 0.
+func estimateTransactionCount(ctx context.Context) (int, error) {
+	var total int
+	err := queryRowReadDB(ctx, "estimate_transactions",
+		"SELECT GREATEST(reltuples, 0)::bigint FROM pg_class WHERE oid = 'transactions'::regclass").Scan(&total)
+	return total, err
+}
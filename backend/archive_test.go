@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestArchiveCutoffUsesAppTimeZone(t *testing.T) {
+	defer func() { appLocation = time.UTC }()
+	appLocation = time.FixedZone("Asia/Tokyo", 9*3600)
+
+	mock := newMockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("WITH RECURSIVE tree .+ created_at < \\(\\$1::date\\)::timestamp AT TIME ZONE \\$2 .+ INSERT INTO transactions_archive .+ WHERE id IN \\(SELECT id FROM tree\\)").
+		WithArgs("2024-01-01", "Asia/Tokyo").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("WITH RECURSIVE tree .+ created_at < \\(\\$1::date\\)::timestamp AT TIME ZONE \\$2 .+ DELETE FROM transactions WHERE id IN \\(SELECT id FROM tree\\)").
+		WithArgs("2024-01-01", "Asia/Tokyo").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	rec := serve(archiveTransactions, "POST", "/transactions/archive?before=2024-01-01", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+	}
+}
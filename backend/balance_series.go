@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// Máximo de días que abarca /summary/balance-series y rango por defecto si no se indica from
+const (
+	maxBalanceSeriesDays     = 366
+	defaultBalanceSeriesDays = 30
+)
+
+const dateLayout = "2006-01-02"
+
+// BalancePoint es el balance acumulado al final de un día
+type BalancePoint struct {
+	Date    string `json:"date"`
+	Balance Amount `json:"balance"`
+}
+
+// Handler para /summary/balance-series (GET: balance acumulado por día entre ?from= y ?to=).
+// ?fill= controla los días sin transacciones: "none" (por defecto) los omite,
+// "forward" repite el último balance conocido y "zero" los emite con balance 0.
+func getBalanceSeries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r, "GET")
+		return
+	}
+
+	query := r.URL.Query()
+	to := appNow() // hoy en APP_TIMEZONE
+	if raw := query.Get("to"); raw != "" {
+		parsed, err := time.Parse(dateLayout, raw)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, msgInvalidDate, "to")
+			return
+		}
+		to = parsed
+	}
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -defaultBalanceSeriesDays)
+	if raw := query.Get("from"); raw != "" {
+		parsed, err := time.Parse(dateLayout, raw)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, msgInvalidDate, "from")
+			return
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		writeError(w, r, http.StatusBadRequest, msgFromAfterTo)
+		return
+	}
+	if to.Sub(from) > maxBalanceSeriesDays*24*time.Hour {
+		writeError(w, r, http.StatusBadRequest, msgRangeTooLarge, maxBalanceSeriesDays)
+		return
+	}
+
+	fill := query.Get("fill")
+	if fill == "" {
+		fill = "none"
+	}
+	if fill != "none" && fill != "forward" && fill != "zero" {
+		writeError(w, r, http.StatusBadRequest, msgInvalidFill)
+		return
+	}
+
+	// El acumulado incluye todo el histórico anterior a 'from' para que el primer punto sea el
+	// balance real. Los días se cuentan en APP_TIMEZONE, como en los filtros from/to de la lista.
+	rows, err := queryReadDB(r.Context(), "balance_series", `
+		SELECT day, balance FROM (
+			SELECT day, SUM(total) OVER (ORDER BY day) AS balance FROM (
+				SELECT (created_at AT TIME ZONE $3)::date AS day, SUM(`+signedSQL()+`) AS total
+				FROM transactions
+				WHERE `+visibleTransactions+` AND created_at < ($2::date + 1)::timestamp AT TIME ZONE $3
+				GROUP BY 1
+			) d
+		) s
+		WHERE day >= $1::date
+		ORDER BY day`, from.Format(dateLayout), to.Format(dateLayout), appLocation.String())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	points := []BalancePoint{}
+	byDay := map[string]Amount{}
+	for rows.Next() {
+		var day time.Time
+		var balance Amount
+		if err := rows.Scan(&day, &balance); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if fill == "none" {
+			points = append(points, BalancePoint{Date: day.Format(dateLayout), Balance: balance})
+			continue
+		}
+		byDay[day.Format(dateLayout)] = balance
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if fill != "none" {
+		var last Amount
+		if fill == "forward" {
+			// Balance de apertura: todo lo anterior a 'from'
+			err := queryRowReadDB(r.Context(), "balance_opening", `
+				SELECT COALESCE(SUM(`+signedSQL()+`), 0)
+				FROM transactions WHERE `+visibleTransactions+` AND created_at < ($1::date)::timestamp AT TIME ZONE $2`,
+				from.Format(dateLayout), appLocation.String()).Scan(&last)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+			key := day.Format(dateLayout)
+			balance, ok := byDay[key]
+			if ok {
+				last = balance
+			} else if fill == "forward" {
+				balance = last
+			}
+			points = append(points, BalancePoint{Date: key, Balance: balance})
+		}
+	}
+
+	writeJSON(w, r, http.StatusOK, points)
+}
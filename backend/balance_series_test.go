@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBalanceSeriesUsesAppTimeZone(t *testing.T) {
+	defer func() { appLocation = time.UTC }()
+	appLocation = time.FixedZone("America/Bogota", -5*3600)
+
+	mock := newMockDB(t)
+	mock.ExpectQuery("\\(created_at AT TIME ZONE \\$3\\)::date AS day, .+ created_at < \\(\\$2::date \\+ 1\\)::timestamp AT TIME ZONE \\$3").
+		WithArgs("2024-03-01", "2024-03-03", "America/Bogota").
+		WillReturnRows(sqlmock.NewRows([]string{"day", "balance"}).
+			AddRow(time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), "10.00"))
+	mock.ExpectQuery("created_at < \\(\\$1::date\\)::timestamp AT TIME ZONE \\$2").
+		WithArgs("2024-03-01", "America/Bogota").
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("5.00"))
+
+	rec := serve(getBalanceSeries, "GET", "/summary/balance-series?from=2024-03-01&to=2024-03-03&fill=forward", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+	}
+}
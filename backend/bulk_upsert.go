@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Máximo de transacciones por petición de /transactions/bulk-upsert
+const maxBulkUpsert = 500
+
+// BulkUpsertItem identifica una transacción procesada por bulk-upsert
+type BulkUpsertItem struct {
+	UUID string `json:"uuid"`
+	ID   int    `json:"id"`
+}
+
+// BulkUpsertResult separa las transacciones creadas de las que ya existían y se actualizaron
+type BulkUpsertResult struct {
+	Created []BulkUpsertItem `json:"created"`
+	Updated []BulkUpsertItem `json:"updated"`
+}
+
+// Handler para /transactions/bulk-upsert (POST: crea o actualiza un lote de transacciones
+// identificadas por su uuid de cliente, todo en una única transacción de base de datos).
+// Si algún elemento no es válido no se escribe nada y se responde 422 con los errores de
+// cada uno. Al actualizar, un created_at o un status omitidos conservan el valor guardado;
+// al crear, el status omitido es posted.
+func bulkUpsertTransactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		methodNotAllowed(w, r, "POST")
+		return
+	}
+
+	var items []Transaction
+	if !decodeJSON(w, r, maxImportBytes, &items) {
+		return
+	}
+	if len(items) == 0 || len(items) > maxBulkUpsert {
+		writeError(w, r, http.StatusBadRequest, msgInvalidBulkSize, maxBulkUpsert)
+		return
+	}
+
+	// Validar todo el lote antes de escribir
+	itemErrors := []ImportRowError{}
+	seen := map[string]bool{}
+	for i := range items {
+		t := &items[i]
+		var problems []string
+		if t.UUID == nil {
+			problems = append(problems, localize(r, msgBulkMissingUUID))
+		} else if uuid, ok := normalizeUUID(*t.UUID); !ok {
+			problems = append(problems, localize(r, msgInvalidUUID))
+		} else if seen[uuid] {
+			problems = append(problems, localize(r, msgBulkDuplicateUUID, uuid))
+		} else {
+			seen[uuid] = true
+			t.UUID = &uuid
+		}
+		if t.Type == "" {
+			t.Type = defaultTransactionType
+		}
+		if t.Description == "" || !validAmount(t.Amount) || !isValidType(t.Type) {
+			problems = append(problems, localize(r, msgInvalidTransaction))
+		}
+		if amountTooLarge(t.Amount) {
+			problems = append(problems, localize(r, msgAmountTooLarge, maxAmount))
+		}
+		if t.Status != "" && !isValidStatus(t.Status) {
+			problems = append(problems, localize(r, msgInvalidStatus))
+		}
+		t.Payee = normalizePayee(t.Payee)
+		t.Reference = normalizeReference(t.Reference)
+		problems = append(problems, checkRules(r, *t)...)
+		if len(problems) > 0 {
+			// Row es la posición en el array, empezando en 1
+			itemErrors = append(itemErrors, ImportRowError{Row: i + 1, Errors: problems})
+		}
+		t.Source = strings.TrimSpace(t.Source)
+		if t.Source == "" {
+			t.Source = defaultSource
+		}
+	}
+	if len(itemErrors) > 0 {
+		writeJSON(w, r, http.StatusUnprocessableEntity, map[string]any{
+			"error":  localize(r, msgBulkInvalidItems, len(itemErrors)),
+			"errors": itemErrors,
+		})
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result := BulkUpsertResult{Created: []BulkUpsertItem{}, Updated: []BulkUpsertItem{}}
+	now := appNow().UTC()
+	for _, t := range items {
+		var createdAt *time.Time
+		if !t.CreatedAt.IsZero() {
+			utc := t.CreatedAt.UTC()
+			createdAt = &utc
+		}
+		var status *string
+		if t.Status != "" {
+			status = &t.Status
+		}
+		var id int
+		var inserted bool
+		// xmax = 0 solo en las filas recién insertadas: distingue creación de actualización
+		err := queryRowTx(ctx, tx, "bulk_upsert_transaction", `
+			INSERT INTO transactions (description, amount, type, source, cleared, payee, is_template, uuid, created_at, reference, status)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE($9::timestamptz, $10), $11, COALESCE($12::varchar, $13))
+			ON CONFLICT (uuid) DO UPDATE SET
+				description = EXCLUDED.description, amount = EXCLUDED.amount, type = EXCLUDED.type,
+				source = EXCLUDED.source, cleared = EXCLUDED.cleared, payee = EXCLUDED.payee,
+				is_template = EXCLUDED.is_template, reference = EXCLUDED.reference,
+				updated_at = CURRENT_TIMESTAMP,
+				created_at = COALESCE($9::timestamptz, transactions.created_at),
+				status = COALESCE($12::varchar, transactions.status)
+			RETURNING id, xmax = 0`,
+			t.Description, storedAmount(t), t.Type, t.Source, t.Cleared, t.Payee, t.IsTemplate, *t.UUID,
+			createdAt, now, t.Reference, status, statusPosted).Scan(&id, &inserted)
+		if isReferenceConflict(err) {
+			writeError(w, r, http.StatusConflict, msgDuplicateReference, *t.Reference)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("%s: %v", *t.UUID, err), http.StatusInternalServerError)
+			return
+		}
+		item := BulkUpsertItem{UUID: *t.UUID, ID: id}
+		if inserted {
+			result.Created = append(result.Created, item)
+		} else {
+			result.Updated = append(result.Updated, item)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, result)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBulkUpsertStatus(t *testing.T) {
+	const uuid = "0b6f8c4e-2a1d-4f4b-9c1e-5d7a3b2c1e0f"
+	pending := statusPending
+	cases := []struct {
+		name   string
+		status string
+		arg    any // valor de $12: nil si se omite, para conservar el guardado o crear como posted
+		code   int
+	}{
+		{"omitido", ``, nil, http.StatusOK},
+		{"pendiente", `,"status":"pending"`, &pending, http.StatusOK},
+		{"inválido", `,"status":"cleared"`, nil, http.StatusUnprocessableEntity},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := newMockDB(t)
+			if c.code == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO transactions .+ status = COALESCE\\(\\$12::varchar, transactions.status\\)").
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+						sqlmock.AnyArg(), uuid, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), c.arg, statusPosted).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "inserted"}).AddRow(1, true))
+				mock.ExpectCommit()
+			}
+
+			body := `[{"uuid":"` + uuid + `","description":"Café","amount":3.5,"type":"expense"` + c.status + `}]`
+			rec := serve(bulkUpsertTransactions, "POST", "/transactions/bulk-upsert", body)
+			if rec.Code != c.code {
+				t.Fatalf("estado %d, se esperaba %d: %s", rec.Code, c.code, rec.Body)
+			}
+		})
+	}
+}
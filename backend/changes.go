@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Índice para leer los cambios por updated_at sin recorrer toda la tabla
+const createUpdatedAtIndexSQL = `
+	CREATE INDEX IF NOT EXISTS transactions_updated_at_idx ON transactions (updated_at)`
+
+// Marcas de borrado para /transactions/changes: un trigger registra el id de cada fila que
+// sale de transactions, ya sea por un borrado individual, uno masivo o el archivado
+const createDeletionsSQL = `
+	CREATE TABLE IF NOT EXISTS transaction_deletions (
+		transaction_id INTEGER NOT NULL,
+		deleted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS transaction_deletions_deleted_at_idx ON transaction_deletions (deleted_at);
+	CREATE OR REPLACE FUNCTION record_transaction_deletion() RETURNS trigger AS $$
+	BEGIN
+		INSERT INTO transaction_deletions (transaction_id) VALUES (OLD.id);
+		RETURN OLD;
+	END
+	$$ LANGUAGE plpgsql;
+	DROP TRIGGER IF EXISTS transactions_record_deletion ON transactions;
+	CREATE TRIGGER transactions_record_deletion AFTER DELETE ON transactions
+		FOR EACH ROW EXECUTE FUNCTION record_transaction_deletion();`
+
+// TransactionChanges es la respuesta de /transactions/changes. ServerTime es el valor de
+// ?since= para la siguiente sincronización; Deleted son los ids que han dejado de existir
+// (borrados o archivados).
+type TransactionChanges struct {
+	ServerTime   time.Time     `json:"server_time"`
+	Transactions []Transaction `json:"transactions"`
+	Deleted      []int         `json:"deleted"`
+}
+
+// Handler para /transactions/changes (GET: transacciones creadas o modificadas después de
+// ?since=, un instante RFC 3339, ordenadas por updated_at, y los ids borrados desde entonces).
+// Devuelve todas las filas, también las plantillas y las divididas, para que el cliente
+// replique la tabla tal cual. Las sincronizaciones consecutivas pueden solaparse: el cliente
+// debe aplicar cada fila como un upsert por id, de modo que repetir una no tiene efecto.
+func getTransactionChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r, "GET")
+		return
+	}
+
+	since, err := time.Parse(time.RFC3339Nano, r.URL.Query().Get("since"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, msgInvalidSince)
+		return
+	}
+
+	// updated_at es la hora de inicio de la transacción que escribe, no la de su commit: una
+	// escritura empezada antes de esta lectura y confirmada después tendría un updated_at
+	// anterior a la hora actual sin aparecer aún. Por eso el corte es el inicio de la
+	// transacción abierta más antigua (o la hora actual si no hay ninguna), y se toma antes
+	// de leer. Lo que quede entre el corte y ahora se vuelve a enviar en la siguiente.
+	changes := TransactionChanges{Transactions: []Transaction{}, Deleted: []int{}}
+	err = queryRowDB(r.Context(), "changes_server_time", `
+		SELECT LEAST(CURRENT_TIMESTAMP, (
+			SELECT min(xact_start) FROM pg_stat_activity
+			WHERE datname = current_database() AND pid <> pg_backend_pid()
+		))`).Scan(&changes.ServerTime)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Se lee del primario: en la réplica podrían faltar cambios anteriores a server_time
+	rows, err := queryDB(r.Context(), "transaction_changes",
+		"SELECT "+transactionColumns+" FROM transactions WHERE updated_at > $1 ORDER BY updated_at, id", since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t Transaction
+		if err := scanTransaction(rows, &t); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		changes.Transactions = append(changes.Transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var deleted pq.Int64Array
+	err = queryRowDB(r.Context(), "transaction_deletions",
+		"SELECT COALESCE(array_agg(transaction_id ORDER BY deleted_at, transaction_id), '{}') FROM transaction_deletions WHERE deleted_at > $1",
+		since).Scan(&deleted)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, id := range deleted {
+		changes.Deleted = append(changes.Deleted, int(id))
+	}
+
+	writeJSON(w, r, http.StatusOK, changes)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestTransactionChanges(t *testing.T) {
+	mock := newMockDB(t)
+	now := time.Date(2024, 6, 2, 9, 0, 0, 0, time.UTC)
+	since := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT LEAST\\(CURRENT_TIMESTAMP, .+ FROM pg_stat_activity").
+		WillReturnRows(sqlmock.NewRows([]string{"now"}).AddRow(now))
+	mock.ExpectQuery("WHERE updated_at > \\$1 ORDER BY updated_at, id").
+		WithArgs(since).
+		WillReturnRows(addTransactionRow(transactionRows(), 7, "Café", "3.50", "expense"))
+	mock.ExpectQuery("FROM transaction_deletions WHERE deleted_at > \\$1").
+		WithArgs(since).
+		WillReturnRows(sqlmock.NewRows([]string{"ids"}).AddRow("{5,9}"))
+
+	rec := serve(getTransactionChanges, "GET", "/transactions/changes?since=2024-06-01T00:00:00Z", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+	}
+	var got TransactionChanges
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.ServerTime.Equal(now) || len(got.Transactions) != 1 || got.Transactions[0].ID != 7 ||
+		len(got.Deleted) != 2 || got.Deleted[0] != 5 || got.Deleted[1] != 9 {
+		t.Errorf("respuesta inesperada: %+v", got)
+	}
+}
+
+func TestTransactionChangesRequiresSince(t *testing.T) {
+	for _, target := range []string{"/transactions/changes", "/transactions/changes?since=ayer"} {
+		rec := serve(getTransactionChanges, "GET", target, "")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: estado %d, se esperaba 400", target, rec.Code)
+		}
+	}
+}
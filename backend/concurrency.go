@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Máximo de peticiones atendidas a la vez (MAX_CONCURRENT_REQUESTS). 0, el valor por
+// defecto, no pone límite. Protege el pool de conexiones a la base de datos.
+var maxConcurrentRequests = 0
+
+// Segundos sugeridos en Retry-After cuando se alcanza el límite de concurrencia
+const concurrencyRetryAfter = 1
+
+// Rutas que no cuentan para el límite: las sondas de salud deben responder aunque el
+// servidor esté saturado
+var concurrencyExemptPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+// concurrencyLimitHandler limita las peticiones en curso con un semáforo de capacidad
+// maxConcurrentRequests. Las que no caben se rechazan al momento con 503 y Retry-After
+// en lugar de esperar en una cola sin límite.
+func concurrencyLimitHandler(h http.Handler) http.Handler {
+	if maxConcurrentRequests <= 0 {
+		return h
+	}
+	slots := make(chan struct{}, maxConcurrentRequests)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if concurrencyExemptPaths[r.URL.Path] {
+			h.ServeHTTP(w, r)
+			return
+		}
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			h.ServeHTTP(w, r)
+		default:
+			w.Header().Set("Retry-After", strconv.Itoa(concurrencyRetryAfter))
+			writeError(w, r, http.StatusServiceUnavailable, msgTooManyRequests)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// loadConfig lee las opciones de la aplicación de las variables de entorno.
+// Un valor inválido detiene el arranque para no funcionar con una configuración inesperada.
+func loadConfig() {
+	loadSlowQueryThreshold()
+	amountAsString = os.Getenv("AMOUNT_AS_STRING") == "true"
+	readOnly = os.Getenv("READ_ONLY") == "true"
+	strictAmountNumbers = os.Getenv("STRICT_AMOUNT_NUMBERS") == "true"
+	allowZeroAmount = os.Getenv("ALLOW_ZERO_AMOUNT") == "true"
+	debugAPIKey = os.Getenv("DEBUG_API_KEY")
+	rounding, err := parseRoundingMode(os.Getenv("SUMMARY_ROUNDING"))
+	if err != nil {
+		log.Fatalf("SUMMARY_ROUNDING inválido: %v", err)
+	}
+	summaryRounding = rounding
+	loadRatioPrecision()
+	defaultTransactionType = os.Getenv("DEFAULT_TRANSACTION_TYPE")
+	if defaultTransactionType != "" && !isValidType(defaultTransactionType) {
+		log.Fatalf("DEFAULT_TRANSACTION_TYPE inválido: %q (usa income o expense)", defaultTransactionType)
+	}
+	if tz := os.Getenv("APP_TIMEZONE"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			log.Fatalf("APP_TIMEZONE inválida: %v", err)
+		}
+		appLocation = loc
+	}
+	if v := os.Getenv("MAX_BODY_BYTES"); v != "" {
+		limit, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || limit <= 0 {
+			log.Fatalf("MAX_BODY_BYTES inválido: %q", v)
+		}
+		maxBodyBytes = limit
+	}
+	archiveAgeDays = positiveIntEnv("ARCHIVE_AGE_DAYS", archiveAgeDays)
+	dataWarningThreshold = positiveIntEnv("DATA_WARNING_THRESHOLD", dataWarningThreshold)
+	debugLogBodies = os.Getenv("DEBUG_LOG_BODIES") == "true"
+	serverTimingEnabled = os.Getenv("SERVER_TIMING") == "true"
+	debugLogBodyMax = positiveIntEnv("DEBUG_LOG_BODY_MAX", debugLogBodyMax)
+	logPreflight = os.Getenv("CORS_LOG_PREFLIGHT") == "true"
+	loadAllowedOrigins()
+	storeSignedAmounts = os.Getenv("STORE_SIGNED_AMOUNTS") == "true"
+	loadRules()
+	loadTLSConfig()
+	loadCurrencyConfig()
+	loadFXRates()
+	healthDBTimeout = durationEnv("HEALTH_DB_TIMEOUT", healthDBTimeout)
+	if v := os.Getenv("SUMMARY_SNAPSHOT_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval < 0 {
+			log.Fatalf("SUMMARY_SNAPSHOT_INTERVAL inválido: %q (usa una duración como 15m, o 0 para desactivarlo)", v)
+		}
+		summarySnapshotInterval = interval
+	}
+	if v := os.Getenv("MAX_CONCURRENT_REQUESTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Fatalf("MAX_CONCURRENT_REQUESTS inválido: %q", v)
+		}
+		maxConcurrentRequests = n
+	}
+	loadAnomalyConfig()
+	if v := os.Getenv("SUMMARY_CACHE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl < 0 {
+			log.Fatalf("SUMMARY_CACHE_TTL inválido: %q (usa una duración como 10s, o 0 para desactivarla)", v)
+		}
+		summaryCacheTTL = ttl
+	}
+	shutdownTimeoutTerm = durationEnv("SHUTDOWN_TIMEOUT_TERM", shutdownTimeoutTerm)
+	shutdownTimeoutInt = durationEnv("SHUTDOWN_TIMEOUT_INT", shutdownTimeoutInt)
+}
+
+// positiveIntEnv lee un entero positivo de la variable de entorno name; si no está definida
+// devuelve def
+func positiveIntEnv(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		log.Fatalf("%s inválido: %q", name, v)
+	}
+	return n
+}
+
+// durationEnv lee una duración positiva de Go ("30s", "2m") de la variable de entorno name;
+// si no está definida devuelve def
+func durationEnv(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Fatalf("%s inválido: %q (usa una duración como 30s)", name, v)
+	}
+	return d
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Lista de orígenes permitidos
+var allowedOrigins = []string{
+	"http://165.22.139.71:8080",
+	"http://localhost:8080",
+	"http://127.0.0.1:8080",
+}
+
+// originPattern es un origen con comodín de subdominio, como https://*.example.com.
+// El esquema es obligatorio; sin puerto se entiende el del esquema (80 o 443), igual que
+// en la cabecera Origin, que lo omite cuando es el predeterminado.
+type originPattern struct {
+	scheme string
+	suffix string // ".example.com"
+	port   string
+}
+
+// Puerto predeterminado de cada esquema admitido en los patrones
+var defaultOriginPorts = map[string]string{"http": "80", "https": "443"}
+
+// Patrones de CORS_ALLOWED_ORIGINS, compilados al arrancar
+var originPatterns []originPattern
+
+// loadAllowedOrigins añade a la lista los orígenes de CORS_ALLOWED_ORIGINS (separados por
+// comas). Los que llevan * se compilan como patrones (https://*.example.com, con esquema
+// obligatorio y el puerto del esquema si no se indica); el resto se comparan exactamente.
+func loadAllowedOrigins() {
+	for _, entry := range splitList(os.Getenv("CORS_ALLOWED_ORIGINS")) {
+		if !strings.Contains(entry, "*") {
+			allowedOrigins = append(allowedOrigins, entry)
+			continue
+		}
+		p, err := parseOriginPattern(entry)
+		if err != nil {
+			log.Fatalf("CORS_ALLOWED_ORIGINS inválido: %v", err)
+		}
+		originPatterns = append(originPatterns, p)
+	}
+}
+
+// parseOriginPattern valida un patrón esquema://*.dominio[:puerto]. El comodín solo puede
+// ocupar el primer nivel del host y tiene que quedar al menos un dominio con punto detrás,
+// para que un patrón como *.com no abra la API a cualquiera. Sin esquema se rechaza: no
+// debe bastar un patrón para aceptar también http cuando se pensaba en https.
+func parseOriginPattern(s string) (originPattern, error) {
+	var p originPattern
+	scheme, rest, ok := strings.Cut(s, "://")
+	if !ok {
+		return p, fmt.Errorf("%q: falta el esquema (http:// o https://)", s)
+	}
+	p.port, ok = defaultOriginPorts[scheme]
+	if !ok {
+		return p, fmt.Errorf("%q: el esquema debe ser http o https", s)
+	}
+	p.scheme = scheme
+	if host, port, ok := strings.Cut(rest, ":"); ok {
+		if port == "" || strings.Trim(port, "0123456789") != "" {
+			return p, fmt.Errorf("%q: puerto inválido", s)
+		}
+		rest, p.port = host, port
+	}
+	domain, ok := strings.CutPrefix(rest, "*.")
+	if !ok || strings.Contains(domain, "*") || !strings.Contains(domain, ".") ||
+		strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return p, fmt.Errorf("%q: usa la forma *.example.com, con el comodín solo al principio", s)
+	}
+	p.suffix = "." + strings.ToLower(domain)
+	return p, nil
+}
+
+// matches indica si el origen (tal como llega en la cabecera Origin) cumple el patrón
+func (p originPattern) matches(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if u.Scheme != p.scheme {
+		return false
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultOriginPorts[u.Scheme]
+	}
+	if port != p.port {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(u.Hostname()), p.suffix)
+}
+
+// originIsAllowed comprueba primero la lista exacta y después los patrones
+func originIsAllowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowedOrigin := range allowedOrigins {
+		if origin == allowedOrigin {
+			return true
+		}
+	}
+	for _, p := range originPatterns {
+		if p.matches(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Si es true se registra cada preflight CORS con su origen, método y cabeceras pedidos y si se
+// permitió (CORS_LOG_PREFLIGHT). Sirve para diagnosticar una lista de orígenes mal configurada.
+var logPreflight = false
+
+// corsHandler añade las cabeceras CORS (para permitir peticiones desde el frontend).
+// allow es la lista de métodos que admite la ruta envuelta.
+func corsHandler(h http.Handler, allow string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Verificar si el origen de la request está permitido
+		origin := r.Header.Get("Origin")
+		originAllowed := originIsAllowed(origin)
+		if originAllowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			// Sin Timing-Allow-Origin el navegador oculta Server-Timing a otros orígenes
+			if serverTimingEnabled {
+				w.Header().Set("Timing-Allow-Origin", origin)
+			}
+		}
+
+		if logPreflight && r.Method == "OPTIONS" {
+			logPreflightRequest(r, origin, originAllowed, allow)
+		}
+
+		w.Header().Set("Access-Control-Allow-Methods", allow)
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		h.ServeHTTP(w, r)
+	})
+}
+
+// logPreflightRequest registra un preflight. Se considera permitido si el origen está en la
+// lista y el método pedido en Access-Control-Request-Method es uno de los de la ruta.
+func logPreflightRequest(r *http.Request, origin string, originAllowed bool, allow string) {
+	method := r.Header.Get("Access-Control-Request-Method")
+	methodAllowed := false
+	for _, m := range strings.Split(allow, ", ") {
+		if m == method {
+			methodAllowed = true
+			break
+		}
+	}
+	log.Printf("preflight CORS %s origin=%q method=%q headers=%q origen_permitido=%t metodo_permitido=%t permitido=%t request_id=%s",
+		r.URL.Path, origin, method, r.Header.Get("Access-Control-Request-Headers"),
+		originAllowed, methodAllowed, originAllowed && methodAllowed, requestIDFromContext(r.Context()))
+}
@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestParseOriginPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		wantErr bool
+	}{
+		{"https://*.example.com", false},
+		{"http://*.example.com:8080", false},
+		{"*.example.com", true}, // el esquema es obligatorio
+		{"ftp://*.example.com", true},
+		{"https://*.com", true},
+		{"https://app.*.example.com", true},
+		{"https://*.example.com:", true},
+	}
+	for _, c := range cases {
+		if _, err := parseOriginPattern(c.pattern); (err != nil) != c.wantErr {
+			t.Errorf("parseOriginPattern(%q): error %v", c.pattern, err)
+		}
+	}
+}
+
+func TestOriginPatternMatchesDefaultPort(t *testing.T) {
+	cases := []struct {
+		pattern string
+		origin  string
+		want    bool
+	}{
+		{"https://*.example.com", "https://app.example.com", true},
+		{"https://*.example.com", "https://app.example.com:443", true},
+		{"https://*.example.com", "https://app.example.com:8443", false},
+		{"https://*.example.com", "http://app.example.com", false},
+		{"http://*.example.com", "http://app.example.com:80", true},
+		{"http://*.example.com:8080", "http://app.example.com:8080", true},
+		{"http://*.example.com:8080", "http://app.example.com", false},
+		{"https://*.example.com:443", "https://app.example.com", true},
+		{"https://*.example.com", "https://example.com.evil.net", false},
+	}
+	for _, c := range cases {
+		p, err := parseOriginPattern(c.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.matches(c.origin); got != c.want {
+			t.Errorf("%s con Origin %s: %t, se esperaba %t", c.pattern, c.origin, got, c.want)
+		}
+	}
+}
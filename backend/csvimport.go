@@ -0,0 +1,541 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Tamaño máximo del CSV aceptado en las importaciones
+const maxImportBytes = 10 << 20
+
+// Máximo de filas con errores detalladas en la respuesta de validación
+const maxReportedRowErrors = 100
+
+// Nombres de cabecera que se reconocen para cada campo al inferir el mapeo de columnas
+// (se comparan en minúsculas y sin espacios alrededor)
+var importHeaderAliases = map[string][]string{
+	"description": {"description", "descripcion", "descripción", "concepto", "concept", "memo", "detail"},
+	"amount":      {"amount", "importe", "monto", "value", "valor"},
+	"type":        {"type", "tipo"},
+	"created_at":  {"created_at", "date", "fecha"},
+	"payee":       {"payee", "beneficiario", "merchant", "comercio"},
+	"source":      {"source", "origen"},
+	// Extractos con el importe en dos columnas: debit (gasto) y credit (ingreso)
+	"debit":  {"debit", "cargo", "debe"},
+	"credit": {"credit", "abono", "haber"},
+}
+
+// Formatos de fecha con nombre para ?date_format=; también se acepta un layout de Go
+var namedDateFormats = map[string]string{
+	"iso":     "2006-01-02",
+	"rfc3339": time.RFC3339,
+	"eu":      "02/01/2006",
+	"us":      "01/02/2006",
+	"dot":     "02.01.2006",
+}
+
+// Formatos que se prueban en orden cuando no se indica date_format. Las fechas con barras se
+// leen como día/mes: un extracto estadounidense (mes/día) necesita date_format=us.
+var defaultImportDateLayouts = []string{"2006-01-02", time.RFC3339, "2006/01/02", "02.01.2006", "02/01/2006"}
+
+// importDateLayouts devuelve los formatos de fecha a probar según ?date_format=
+// (un nombre de namedDateFormats o un layout de Go con la fecha de referencia 2006-01-02)
+func importDateLayouts(q url.Values) ([]string, error) {
+	raw := strings.TrimSpace(q.Get("date_format"))
+	if raw == "" {
+		return defaultImportDateLayouts, nil
+	}
+	if layout, ok := namedDateFormats[strings.ToLower(raw)]; ok {
+		return []string{layout}, nil
+	}
+	if !validDateLayout(raw) {
+		return nil, newAPIError(msgImportInvalidDateFormat, raw)
+	}
+	return []string{raw}, nil
+}
+
+// Fecha de prueba para validar layouts: día, mes y año distintos entre sí y de los valores
+// por defecto de time.Parse, para que un layout al que le falte alguno no pase por casualidad
+var dateLayoutProbe = time.Date(2021, time.November, 23, 0, 0, 0, 0, time.UTC)
+
+// validDateLayout indica si layout conserva una fecha completa: formatear dateLayoutProbe con
+// él y volver a leerla debe dar el mismo día, mes y año
+func validDateLayout(layout string) bool {
+	parsed, err := time.Parse(layout, dateLayoutProbe.Format(layout))
+	if err != nil {
+		return false
+	}
+	y, m, d := parsed.Date()
+	return y == dateLayoutProbe.Year() && m == dateLayoutProbe.Month() && d == dateLayoutProbe.Day()
+}
+
+// parseImportDate prueba los formatos en orden; las fechas sin zona se interpretan en APP_TIMEZONE
+func parseImportDate(raw string, layouts []string) (time.Time, bool) {
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, raw, appLocation); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// importMapping asocia cada campo de la transacción con el índice de su columna en el CSV
+type importMapping map[string]int
+
+// ImportRowError son los problemas de una fila; Row es el número de línea (la cabecera es la 1)
+type ImportRowError struct {
+	Row    int      `json:"row"`
+	Errors []string `json:"errors"`
+}
+
+// ImportValidation es el resultado de validar un CSV sin importarlo
+type ImportValidation struct {
+	Mapping   map[string]string `json:"mapping"` // campo -> cabecera de la columna detectada
+	Missing   []string          `json:"missing"` // campos obligatorios sin columna
+	Rows      int               `json:"rows"`
+	Valid     int               `json:"valid"`
+	Invalid   int               `json:"invalid"`
+	RowErrors []ImportRowError  `json:"row_errors"` // como mucho maxReportedRowErrors
+	// Con ?lenient=true, incumplimientos de reglas de las filas válidas; como mucho maxReportedRowErrors
+	RowWarnings []ImportRowError `json:"row_warnings,omitempty"`
+}
+
+// inferImportMapping busca en la cabecera una columna para cada campo conocido
+func inferImportMapping(header []string) importMapping {
+	mapping := importMapping{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for field, aliases := range importHeaderAliases {
+			if _, done := mapping[field]; done {
+				continue
+			}
+			for _, alias := range aliases {
+				if name == alias {
+					mapping[field] = i
+				}
+			}
+		}
+	}
+	return mapping
+}
+
+// applyMappingOverrides aplica el mapeo explícito de la petición sobre el inferido:
+// ?map_description=Memo&map_debit=Debit asocia cada campo con la columna de esa cabecera
+// (sin distinguir mayúsculas)
+func applyMappingOverrides(mapping importMapping, header []string, q url.Values) error {
+	for field := range importHeaderAliases {
+		name := strings.TrimSpace(q.Get("map_" + field))
+		if name == "" {
+			continue
+		}
+		i := slices.IndexFunc(header, func(h string) bool { return strings.EqualFold(strings.TrimSpace(h), name) })
+		if i < 0 {
+			return newAPIError(msgImportUnknownColumn, name, field)
+		}
+		mapping[field] = i
+	}
+	return nil
+}
+
+// missingImportFields devuelve los campos obligatorios sin columna: la descripción y el
+// importe, que puede venir en una columna amount o repartido en debit/credit
+func missingImportFields(mapping importMapping) []string {
+	missing := []string{}
+	if _, ok := mapping["description"]; !ok {
+		missing = append(missing, "description")
+	}
+	_, hasAmount := mapping["amount"]
+	_, hasDebit := mapping["debit"]
+	_, hasCredit := mapping["credit"]
+	if !hasAmount && !hasDebit && !hasCredit {
+		missing = append(missing, "amount")
+	}
+	return missing
+}
+
+// parseImportRow convierte una fila del CSV en transacción según el mapeo y devuelve todos
+// los problemas encontrados, ya traducidos. Es la misma conversión que usará la importación.
+func parseImportRow(r *http.Request, record []string, mapping importMapping, dateLayouts []string) (Transaction, []string) {
+	var t Transaction
+	var problems []string
+	cell := func(field string) string {
+		if i, ok := mapping[field]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	t.Description = cell("description")
+	if t.Description == "" {
+		problems = append(problems, localize(r, msgImportEmptyDescription))
+	}
+	t.Type = strings.ToLower(cell("type"))
+	if t.Type != "" && !isValidType(t.Type) {
+		problems = append(problems, localize(r, msgImportInvalidType, t.Type))
+	}
+	raw := cell("amount")
+	if _, ok := mapping["amount"]; !ok {
+		// Importe en columnas debit/credit: la que tenga valor decide el tipo
+		debit, credit := cell("debit"), cell("credit")
+		switch {
+		case debit != "" && credit != "":
+			problems = append(problems, localize(r, msgImportDebitAndCredit))
+		case debit != "":
+			raw, t.Type = strings.TrimPrefix(debit, "-"), "expense"
+		case credit != "":
+			raw, t.Type = strings.TrimPrefix(credit, "-"), "income"
+		}
+	}
+	amount, err := newAmount(raw)
+	switch {
+	case err != nil:
+		problems = append(problems, localize(r, msgImportInvalidAmount, raw))
+	case amount.IsZero() && (!allowZeroAmount || t.Type == ""):
+		problems = append(problems, localize(r, msgZeroAmount))
+	case amountTooLarge(amount):
+		problems = append(problems, localize(r, msgAmountTooLarge, maxAmount))
+	case t.Type == "":
+		// Sin tipo, el signo del importe decide: negativo es un gasto
+		t.Amount = amount
+		if err := inferTypeFromSign(&t); err != nil {
+			problems = append(problems, errorText(r, err))
+		}
+	default:
+		// Con tipo explícito se guarda el valor absoluto, venga con el signo que venga
+		t.Amount = amount
+		if amount.IsNegative() {
+			t.Amount = amount.Neg()
+		}
+	}
+	if raw := cell("created_at"); raw != "" {
+		created, ok := parseImportDate(raw, dateLayouts)
+		if !ok {
+			problems = append(problems, localize(r, msgImportInvalidDate, raw))
+		}
+		t.CreatedAt = created
+	}
+	t.Payee = normalizePayee(ptr(cell("payee")))
+	t.Source = cell("source")
+	return t, problems
+}
+
+// importCSV es el CSV de una importación con la cabecera ya leída, el mapeo de columnas
+// y los formatos de fecha de la petición
+type importCSV struct {
+	body        []byte // cuerpo completo, para recortar la línea original de cada fila
+	reader      *csv.Reader
+	header      []string
+	mapping     importMapping
+	dateLayouts []string
+	lenient     bool // ?lenient=true: las reglas de negocio solo generan avisos
+}
+
+// openImportCSV lee el cuerpo de la petición y prepara el CSV: la cabecera, el mapeo inferido
+// con las correcciones de ?map_<campo>=, los formatos de ?date_format= y ?lenient=. Si algo falla responde
+// con el error y devuelve false.
+func openImportCSV(w http.ResponseWriter, r *http.Request) (*importCSV, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, msgBodyTooLarge, tooLarge.Limit)
+			return nil, false
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.FieldsPerRecord = -1 // las filas cortas se informan como errores de fila
+	header, err := reader.Read()
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, msgImportNoHeader)
+		return nil, false
+	}
+	mapping := inferImportMapping(header)
+	if err := applyMappingOverrides(mapping, header, r.URL.Query()); err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return nil, false
+	}
+	dateLayouts, err := importDateLayouts(r.URL.Query())
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return nil, false
+	}
+	lenient := r.URL.Query().Get("lenient") == "true"
+	return &importCSV{body: body, reader: reader, header: header, mapping: mapping, dateLayouts: dateLayouts, lenient: lenient}, true
+}
+
+// importRow es una fila del CSV: su número de línea, el registro original y los bytes de los
+// que salió, la transacción que resulta, los problemas que impiden importarla y los avisos
+// que no lo impiden
+type importRow struct {
+	line        int
+	record      []string
+	raw         string
+	transaction Transaction
+	problems    []string
+	warnings    []string
+}
+
+// next lee la siguiente fila y la convierte en transacción con sus problemas: los de la
+// conversión y, si la supera, los incumplimientos de las reglas de negocio, igual que al crear.
+// En modo lenient los incumplimientos son avisos, pero un error de conversión sigue siendo un
+// problema. Devuelve io.EOF al acabar el CSV.
+func (c *importCSV) next(r *http.Request) (importRow, error) {
+	start := c.reader.InputOffset()
+	record, err := c.reader.Read()
+	if err == io.EOF {
+		return importRow{}, err
+	}
+	line, _ := c.reader.FieldPos(0)
+	row := importRow{line: line, record: record, raw: rawImportLine(c.body[start:c.reader.InputOffset()])}
+	if err != nil {
+		row.problems = []string{err.Error()}
+		return row, nil
+	}
+	row.transaction, row.problems = parseImportRow(r, record, c.mapping, c.dateLayouts)
+	if len(row.problems) > 0 {
+		return row, nil
+	}
+	if violations := checkRules(r, row.transaction); c.lenient {
+		row.warnings = violations
+	} else {
+		row.problems = violations
+	}
+	return row, nil
+}
+
+// Handler para /transactions/import/validate (POST: analiza un CSV y devuelve el mapeo de
+// columnas detectado, cuántas filas son válidas y los errores de cada fila. No escribe nada).
+// El mapeo inferido de la cabecera se puede corregir con ?map_<campo>=<cabecera> y el formato
+// de las fechas se fija con ?date_format= (iso, eu, us, dot, rfc3339 o un layout de Go).
+func validateImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		methodNotAllowed(w, r, "POST")
+		return
+	}
+
+	in, ok := openImportCSV(w, r)
+	if !ok {
+		return
+	}
+
+	result := ImportValidation{Mapping: map[string]string{}, Missing: missingImportFields(in.mapping), RowErrors: []ImportRowError{}}
+	for field, i := range in.mapping {
+		result.Mapping[field] = in.header[i]
+	}
+	if len(result.Missing) > 0 {
+		writeJSON(w, r, http.StatusUnprocessableEntity, result)
+		return
+	}
+
+	for {
+		row, err := in.next(r)
+		if err == io.EOF {
+			break
+		}
+		result.Rows++
+		if len(row.problems) == 0 {
+			result.Valid++
+			if len(row.warnings) > 0 && len(result.RowWarnings) < maxReportedRowErrors {
+				result.RowWarnings = append(result.RowWarnings, ImportRowError{Row: row.line, Errors: row.warnings})
+			}
+			continue
+		}
+		result.Invalid++
+		if len(result.RowErrors) < maxReportedRowErrors {
+			result.RowErrors = append(result.RowErrors, ImportRowError{Row: row.line, Errors: row.problems})
+		}
+	}
+
+	writeJSON(w, r, http.StatusOK, result)
+}
+
+// Origen de las transacciones importadas de un CSV sin columna source
+const importSource = "import"
+
+// Columnas que solo rellena la importación: no forman parte de Transaction, pero el archivo
+// las conserva
+const importColumns = "content_hash, raw_source"
+
+// Índice único sobre content_hash: una fila importada no puede estar dos veces aunque dos
+// importaciones del mismo extracto se crucen. Sustituye al índice no único anterior.
+const createContentHashIndexSQL = `
+	DROP INDEX IF EXISTS transactions_content_hash_idx;
+	CREATE UNIQUE INDEX IF NOT EXISTS transactions_content_hash_key ON transactions (content_hash)`
+
+// ImportResult es el resultado de una importación: los ids creados, en el orden del CSV, y
+// las líneas que se han saltado por estar ya importadas
+type ImportResult struct {
+	Imported    int   `json:"imported"`
+	IDs         []int `json:"ids"`
+	Skipped     int   `json:"skipped_duplicates"`
+	SkippedRows []int `json:"skipped_rows"`
+}
+
+// importedWithWarnings es la respuesta de una importación en modo lenient: el resultado con
+// los incumplimientos de reglas de cada fila importada (vacío si no hubo ninguno)
+type importedWithWarnings struct {
+	ImportResult
+	Warnings []ImportRowError `json:"warnings"`
+}
+
+// rawImportLine devuelve los bytes que consumió el lector para un registro, tal cual venían
+// en el fichero, para guardarlos en raw_source. Solo se quitan las líneas vacías que el lector
+// salta antes del registro y el salto de línea final; los de dentro de un campo entre
+// comillas se conservan.
+func rawImportLine(consumed []byte) string {
+	return strings.Trim(string(consumed), "\r\n")
+}
+
+// importRowHash identifica una fila del CSV por su contenido. occurrence distingue las filas
+// idénticas dentro del mismo fichero (dos cafés iguales el mismo día), de modo que volver a
+// importar el fichero las salta todas pero importarlo por primera vez no pierde ninguna.
+func importRowHash(record []string, occurrence int) string {
+	h := sha256.New()
+	for _, cell := range record {
+		// Se incluye la longitud de cada celda para que "a,bc" y "ab,c" no coincidan
+		h.Write([]byte(strconv.Itoa(len(cell)) + ":" + strings.TrimSpace(cell)))
+	}
+	h.Write([]byte("#" + strconv.Itoa(occurrence)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Handler para /transactions/import (POST: importa las filas de un CSV). Acepta las mismas
+// opciones que /transactions/import/validate y valida cada fila igual: si alguna no es válida
+// responde 422 con los errores y no importa ninguna. Todas se insertan en una misma transacción.
+// Las filas cuyo content_hash ya está en transactions o en el archivo se saltan, así que
+// importar dos veces el mismo extracto no duplica los datos. Con ?lenient=true, como al crear, las filas que
+// incumplen reglas de negocio se importan igualmente y los incumplimientos vuelven como avisos.
+func importTransactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		methodNotAllowed(w, r, "POST")
+		return
+	}
+
+	in, ok := openImportCSV(w, r)
+	if !ok {
+		return
+	}
+	if missing := missingImportFields(in.mapping); len(missing) > 0 {
+		writeError(w, r, http.StatusUnprocessableEntity, msgImportMissingFields, strings.Join(missing, ", "))
+		return
+	}
+
+	// Validar todo el CSV antes de escribir
+	var rows []importRow
+	var hashes []string
+	occurrences := map[string]int{}
+	rowErrors := []ImportRowError{}
+	invalid := 0
+	for {
+		row, err := in.next(r)
+		if err == io.EOF {
+			break
+		}
+		if len(row.problems) > 0 {
+			invalid++
+			if len(rowErrors) < maxReportedRowErrors {
+				rowErrors = append(rowErrors, ImportRowError{Row: row.line, Errors: row.problems})
+			}
+			continue
+		}
+		first := importRowHash(row.record, 0)
+		hashes = append(hashes, importRowHash(row.record, occurrences[first]))
+		occurrences[first]++
+		rows = append(rows, row)
+	}
+	if invalid > 0 {
+		writeJSON(w, r, http.StatusUnprocessableEntity, map[string]any{
+			"error":  localize(r, msgImportInvalidRows, invalid),
+			"errors": rowErrors,
+		})
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// El índice único solo cubre transactions: las filas ya archivadas se buscan aparte
+	var found pq.StringArray
+	err = queryRowTx(ctx, tx, "import_existing_hashes", `
+		SELECT COALESCE(array_agg(content_hash), '{}') FROM (
+			SELECT content_hash FROM transactions WHERE content_hash = ANY($1)
+			UNION ALL
+			SELECT content_hash FROM transactions_archive WHERE content_hash = ANY($1)
+		) h`,
+		pq.Array(hashes)).Scan(&found)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	existing := map[string]bool{}
+	for _, hash := range found {
+		existing[hash] = true
+	}
+
+	result := ImportResult{IDs: []int{}, SkippedRows: []int{}}
+	warnings := []ImportRowError{}
+	for i, row := range rows {
+		if existing[hashes[i]] {
+			result.SkippedRows = append(result.SkippedRows, row.line)
+			continue
+		}
+		t := row.transaction
+		if t.Source == "" {
+			t.Source = importSource
+		}
+		if t.CreatedAt.IsZero() {
+			t.CreatedAt = appNow()
+		}
+		// Si otra importación insertó la fila después de la búsqueda, ON CONFLICT no devuelve
+		// ninguna fila y se cuenta como duplicada
+		var id int
+		err := queryRowTx(ctx, tx, "import_transaction",
+			"INSERT INTO transactions(description, amount, type, source, payee, created_at, status, content_hash, raw_source) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (content_hash) DO NOTHING RETURNING id",
+			t.Description, storedAmount(t), t.Type, t.Source, t.Payee, t.CreatedAt.UTC(), statusPosted, hashes[i], row.raw).Scan(&id)
+		if err == sql.ErrNoRows {
+			result.SkippedRows = append(result.SkippedRows, row.line)
+			continue
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result.IDs = append(result.IDs, id)
+		if len(row.warnings) > 0 {
+			warnings = append(warnings, ImportRowError{Row: row.line, Errors: row.warnings})
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	result.Imported = len(result.IDs)
+	result.Skipped = len(result.SkippedRows)
+
+	if in.lenient {
+		writeJSON(w, r, http.StatusCreated, importedWithWarnings{ImportResult: result, Warnings: warnings})
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, result)
+}
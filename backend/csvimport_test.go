@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestValidateImportRunsRules(t *testing.T) {
+	defer func() { transactionRules = nil }()
+	transactionRules = []transactionRule{maxDescriptionLengthRule(map[string]int{"expense": 5})}
+
+	body := "description,amount\nCafé,-3.50\nSupermercado,-42.10\nNómina,1500\nSin importe,0\n"
+	rec := serve(validateImport, "POST", "/transactions/import/validate", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+	}
+	var got ImportValidation
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Rows != 4 || got.Valid != 2 || got.Invalid != 2 {
+		t.Fatalf("filas %d, válidas %d, inválidas %d; se esperaba 4, 2, 2", got.Rows, got.Valid, got.Invalid)
+	}
+	// La línea 3 (Supermercado) incumple la regla y la 5 no tiene importe
+	if len(got.RowErrors) != 2 || got.RowErrors[0].Row != 3 || got.RowErrors[1].Row != 5 {
+		t.Errorf("errores de fila %+v", got.RowErrors)
+	}
+}
+
+// expectExistingHashes espera la búsqueda de filas ya importadas, en transactions y en el
+// archivo, y devuelve las indicadas
+func expectExistingHashes(mock sqlmock.Sqlmock, found ...string) {
+	mock.ExpectQuery("SELECT COALESCE\\(array_agg\\(content_hash\\), '\\{\\}'\\) FROM \\(.+FROM transactions WHERE .+FROM transactions_archive WHERE").
+		WillReturnRows(sqlmock.NewRows([]string{"hashes"}).AddRow("{" + strings.Join(found, ",") + "}"))
+}
+
+// expectImportInsert espera el INSERT de una fila importada con su línea original
+func expectImportInsert(mock sqlmock.Sqlmock, description, typ string, createdAt any, raw string, id int) {
+	mock.ExpectQuery("INSERT INTO transactions").
+		WithArgs(description, sqlmock.AnyArg(), typ, importSource, nil, createdAt, statusPosted, sqlmock.AnyArg(), raw).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+}
+
+func TestImportTransactions(t *testing.T) {
+	mock := newMockDB(t)
+	mock.ExpectBegin()
+	expectExistingHashes(mock)
+	expectImportInsert(mock, "Café", "expense", sqlmock.AnyArg(), "01/03/2024,Café,3.50,", 10)
+	expectImportInsert(mock, "Nómina", "income", sqlmock.AnyArg(), "02/03/2024,Nómina,,1500", 11)
+	mock.ExpectCommit()
+
+	body := "Date,Memo,Debit,Credit\n01/03/2024,Café,3.50,\n02/03/2024,Nómina,,1500\n"
+	rec := serve(importTransactions, "POST", "/transactions/import?map_description=Memo&date_format=eu", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+	}
+	var got ImportResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Imported != 2 || len(got.IDs) != 2 || got.IDs[0] != 10 || got.IDs[1] != 11 {
+		t.Errorf("resultado %+v", got)
+	}
+}
+
+func TestImportTransactionsKeepsRawLine(t *testing.T) {
+	mock := newMockDB(t)
+	mock.ExpectBegin()
+	expectExistingHashes(mock)
+	// Comillas, CRLF, la línea vacía intermedia y el salto dentro del campo llegan tal cual
+	expectImportInsert(mock, "Café, con leche", "expense", sqlmock.AnyArg(), `"Café, con leche", -3.50`, 1)
+	expectImportInsert(mock, "Regalo\nde cumpleaños", "expense", sqlmock.AnyArg(), "\"Regalo\r\nde cumpleaños\",-20", 2)
+	mock.ExpectCommit()
+
+	body := "description,amount\r\n\"Café, con leche\", -3.50\r\n\r\n\"Regalo\r\nde cumpleaños\",-20\r\n"
+	rec := serve(importTransactions, "POST", "/transactions/import", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestImportTransactionsRejectsInvalidRows(t *testing.T) {
+	newMockDB(t) // no se espera ninguna consulta: con una fila inválida no se escribe nada
+
+	body := "description,amount,date\nCafé,-3.50,2024-03-01\nCena,abc,2024-03-02\n"
+	rec := serve(importTransactions, "POST", "/transactions/import", body)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("estado %d, se esperaba 422: %s", rec.Code, rec.Body)
+	}
+	var got struct {
+		Errors []ImportRowError `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Errors) != 1 || got.Errors[0].Row != 3 {
+		t.Errorf("errores %+v", got.Errors)
+	}
+
+	rec = serve(importTransactions, "POST", "/transactions/import", "date,payee\n2024-03-01,Bar\n")
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("sin columnas obligatorias: estado %d, se esperaba 422", rec.Code)
+	}
+}
+
+func TestImportDateLayoutsValidation(t *testing.T) {
+	valid := []string{"02/01/2006", "2006-01-02 15:04", "2 Jan 06", "January 2, 2006", "01.02.06"}
+	for _, layout := range valid {
+		if _, err := importDateLayouts(url.Values{"date_format": {layout}}); err != nil {
+			t.Errorf("%q: %v", layout, err)
+		}
+	}
+	// Solo el año, sin día o sin mes, sin año, o texto que no es un layout
+	invalid := []string{"06", "2006", "2006-01", "01/2006", "2006-02", "02/01", "fecha"}
+	for _, layout := range invalid {
+		_, err := importDateLayouts(url.Values{"date_format": {layout}})
+		if apiErrorKey(err) != msgImportInvalidDateFormat {
+			t.Errorf("%q: error %v, se esperaba msgImportInvalidDateFormat", layout, err)
+		}
+	}
+}
+
+func TestImportTransactionsDateFormat(t *testing.T) {
+	defer func() { appLocation = time.UTC }()
+	appLocation = time.FixedZone("UTC+2", 2*3600)
+
+	// Con date_format=us, 03/04/2024 es el 4 de marzo a medianoche en APP_TIMEZONE
+	mock := newMockDB(t)
+	mock.ExpectBegin()
+	expectExistingHashes(mock)
+	expectImportInsert(mock, "Café", "expense", appTimeArg{time.Date(2024, 3, 4, 0, 0, 0, 0, appLocation), 0}, "03/04/2024,Café,-3.50", 1)
+	mock.ExpectCommit()
+
+	body := "date,description,amount\n03/04/2024,Café,-3.50\n"
+	rec := serve(importTransactions, "POST", "/transactions/import?date_format=us", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+	}
+
+	// Con iso, la misma fecha no se reconoce y la fila se rechaza
+	rec = serve(importTransactions, "POST", "/transactions/import?date_format=iso", body)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("date_format=iso: estado %d, se esperaba 422", rec.Code)
+	}
+}
+
+func TestImportTransactionsSkipsDuplicates(t *testing.T) {
+	// La segunda y la tercera fila son idénticas: solo la segunda estaba ya importada
+	body := "date,description,amount\n2024-03-01,Café,-3.50\n2024-03-02,Café,-3.50\n2024-03-02,Café,-3.50\n"
+	second := []string{"2024-03-02", "Café", "-3.50"}
+	if importRowHash(second, 0) == importRowHash(second, 1) {
+		t.Fatal("dos apariciones de la misma fila tienen el mismo hash")
+	}
+
+	mock := newMockDB(t)
+	mock.ExpectBegin()
+	expectExistingHashes(mock, importRowHash(second, 0))
+	expectImportInsert(mock, "Café", "expense", sqlmock.AnyArg(), "2024-03-01,Café,-3.50", 1)
+	expectImportInsert(mock, "Café", "expense", sqlmock.AnyArg(), "2024-03-02,Café,-3.50", 2)
+	mock.ExpectCommit()
+
+	rec := serve(importTransactions, "POST", "/transactions/import", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+	}
+	var got ImportResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Imported != 2 || got.Skipped != 1 || len(got.SkippedRows) != 1 || got.SkippedRows[0] != 3 {
+		t.Errorf("resultado %+v", got)
+	}
+}
+
+func TestGetTransactionIncludeRaw(t *testing.T) {
+	raw := "01/03/2024,Café,3.50,"
+	cases := []struct {
+		target string
+		query  string
+		want   *string
+	}{
+		{"/transaction/42", "SELECT id, .+, updated_at FROM transactions WHERE id = \\$1", nil},
+		{"/transaction/42?include_raw=true", "SELECT id, .+, updated_at, raw_source FROM transactions WHERE id = \\$1", &raw},
+	}
+	for _, c := range cases {
+		mock := newMockDB(t)
+		rows := addTransactionRow(transactionRows(), 42, "Café", "3.50", "expense")
+		if c.want != nil {
+			rows = sqlmock.NewRows(strings.Split(selectTransactionColumns(true), ", ")).
+				AddRow(append(transactionRowValues(42, "Café", "3.50", "expense"), raw)...)
+		}
+		mock.ExpectQuery(c.query).WithArgs(42).WillReturnRows(rows)
+
+		rec := serve(handleTransactionByID, "GET", c.target, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: estado %d: %s", c.target, rec.Code, rec.Body)
+		}
+		var got map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		value, present := got["raw_source"]
+		if c.want == nil && present {
+			t.Errorf("%s: raw_source presente sin include_raw", c.target)
+		}
+		if c.want != nil && value != *c.want {
+			t.Errorf("%s: raw_source %v, se esperaba %q", c.target, value, *c.want)
+		}
+	}
+}
+
+func TestImportTransactionsConcurrentDuplicate(t *testing.T) {
+	// Otra importación insertó la segunda fila entre la búsqueda y el INSERT
+	mock := newMockDB(t)
+	mock.ExpectBegin()
+	expectExistingHashes(mock)
+	expectImportInsert(mock, "Café", "expense", sqlmock.AnyArg(), "Café,-3.50", 1)
+	mock.ExpectQuery("INSERT INTO transactions.+ ON CONFLICT \\(content_hash\\) DO NOTHING RETURNING id").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	rec := serve(importTransactions, "POST", "/transactions/import", "description,amount\nCafé,-3.50\nSupermercado,-42.10\n")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+	}
+	var got ImportResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Imported != 1 || got.Skipped != 1 || len(got.SkippedRows) != 1 || got.SkippedRows[0] != 3 {
+		t.Errorf("resultado %+v", got)
+	}
+}
+
+func TestImportTransactionsLenient(t *testing.T) {
+	defer func() { transactionRules = nil }()
+	transactionRules = []transactionRule{maxDescriptionLengthRule(map[string]int{"expense": 5})}
+	body := "description,amount\nCafé,-3.50\nSupermercado,-42.10\n"
+
+	// Sin lenient, la regla incumplida rechaza todo el CSV
+	newMockDB(t)
+	rec := serve(importTransactions, "POST", "/transactions/import", body)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("sin lenient: estado %d, se esperaba 422: %s", rec.Code, rec.Body)
+	}
+
+	mock := newMockDB(t)
+	mock.ExpectBegin()
+	expectExistingHashes(mock)
+	expectImportInsert(mock, "Café", "expense", sqlmock.AnyArg(), "Café,-3.50", 1)
+	expectImportInsert(mock, "Supermercado", "expense", sqlmock.AnyArg(), "Supermercado,-42.10", 2)
+	mock.ExpectCommit()
+	rec = serve(importTransactions, "POST", "/transactions/import?lenient=true", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("lenient: estado %d: %s", rec.Code, rec.Body)
+	}
+	var got importedWithWarnings
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Imported != 2 || len(got.Warnings) != 1 || got.Warnings[0].Row != 3 {
+		t.Errorf("resultado %+v", got)
+	}
+
+	// Un error de conversión sigue rechazando la importación aunque sea lenient
+	newMockDB(t)
+	rec = serve(importTransactions, "POST", "/transactions/import?lenient=true", "description,amount\nCafé,abc\n")
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("lenient con importe inválido: estado %d, se esperaba 422", rec.Code)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Moneda de las transacciones (DEFAULT_CURRENCY, código ISO 4217). Las transacciones aún no
+// guardan su propia moneda, así que todas usan esta.
+var defaultCurrency = "USD"
+
+// Símbolos de las monedas más comunes; las demás se muestran con su código ISO
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"MXN": "$",
+	"ARS": "$",
+	"CLP": "$",
+	"COP": "$",
+	"BRL": "R$",
+	"INR": "₹",
+}
+
+func loadCurrencyConfig() {
+	if v := os.Getenv("DEFAULT_CURRENCY"); v != "" {
+		code := strings.ToUpper(strings.TrimSpace(v))
+		if len(code) != 3 {
+			log.Fatalf("DEFAULT_CURRENCY inválido: %q (usa un código ISO como EUR)", v)
+		}
+		defaultCurrency = code
+	}
+}
+
+// formatAmount devuelve el importe con el símbolo de la moneda ("$19.99"), o con su código
+// ISO si no tiene símbolo conocido ("CHF 19.99")
+func formatAmount(a Amount, currency string) string {
+	if symbol, ok := currencySymbols[currency]; ok {
+		return symbol + a.String()
+	}
+	return currency + " " + a.String()
+}
+
+// wantsSymbol indica si la petición pidió formatted_amount con ?with_symbol=true
+func wantsSymbol(r *http.Request) bool {
+	return r.URL.Query().Get("with_symbol") == "true"
+}
+
+// addFormattedAmounts rellena formatted_amount de cada transacción
+func addFormattedAmounts(transactions []Transaction) {
+	for i := range transactions {
+		transactions[i].FormattedAmount = formatAmount(transactions[i].Amount, defaultCurrency)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Umbral a partir del cual una consulta se registra como lenta (SLOW_QUERY_MS)
+var slowQueryThreshold = 500 * time.Millisecond
+
+// Logger JSON para las advertencias de consultas lentas
+var slowQueryLogger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+// loadSlowQueryThreshold lee SLOW_QUERY_MS; si no es válido se mantiene el valor por defecto
+func loadSlowQueryThreshold() {
+	v := os.Getenv("SLOW_QUERY_MS")
+	if v == "" {
+		return
+	}
+	ms, err := strconv.Atoi(v)
+	if err != nil || ms < 0 {
+		slowQueryLogger.Warn("SLOW_QUERY_MS inválido, se usa el valor por defecto", "value", v)
+		return
+	}
+	slowQueryThreshold = time.Duration(ms) * time.Millisecond
+}
+
+// observeQuery registra una advertencia si la consulta superó el umbral configurado y
+// suma su duración al Server-Timing de la petición
+func observeQuery(ctx context.Context, name string, start time.Time) {
+	elapsed := time.Since(start)
+	addQueryTiming(ctx, elapsed)
+	if elapsed < slowQueryThreshold {
+		return
+	}
+	slowQueryLogger.Warn("consulta lenta",
+		"query", name,
+		"duration_ms", elapsed.Milliseconds(),
+		"request_id", requestIDFromContext(ctx),
+	)
+}
+
+// readDB es el pool para consultas de solo lectura (listas y resúmenes). Apunta a la réplica
+// si DB_REPLICA_HOST está configurado; si no, es el mismo pool que db.
+var readDB *sql.DB
+
+// replicaHealthy indica si la réplica respondió a la última comprobación. Mientras no
+// responda las lecturas van al primario.
+var replicaHealthy atomic.Bool
+
+// Cada cuánto se comprueba si la réplica responde
+const replicaCheckInterval = 10 * time.Second
+
+// openReadReplica abre el pool de la réplica de lectura y la vigila en segundo plano. Si no
+// responde al arrancar se registra y las lecturas usan el primario hasta que vuelva.
+func openReadReplica(connStr string) {
+	replica, err := sql.Open("postgres", connStr)
+	if err != nil {
+		log.Printf("Configuración de la réplica de lectura inválida, se usará el primario: %v", err)
+		readDB = db
+		return
+	}
+	readDB = replica
+	if err := replica.Ping(); err != nil {
+		log.Printf("No se pudo conectar a la réplica de lectura, se usará el primario: %v", err)
+	} else {
+		log.Println("Conectado a la réplica de lectura de PostgreSQL")
+		replicaHealthy.Store(true)
+	}
+	go watchReadReplica(replica)
+}
+
+// watchReadReplica hace ping a la réplica cada replicaCheckInterval y actualiza replicaHealthy
+func watchReadReplica(replica *sql.DB) {
+	for range time.Tick(replicaCheckInterval) {
+		ctx, cancel := context.WithTimeout(context.Background(), replicaCheckInterval/2)
+		err := replica.PingContext(ctx)
+		cancel()
+		setReplicaHealth(err)
+	}
+}
+
+// setReplicaHealth marca la réplica como disponible o no y registra los cambios de estado
+func setReplicaHealth(err error) {
+	healthy := err == nil
+	if replicaHealthy.Swap(healthy) == healthy {
+		return
+	}
+	if healthy {
+		log.Println("La réplica de lectura vuelve a responder")
+	} else {
+		log.Printf("La réplica de lectura no responde, se usará el primario: %v", err)
+	}
+}
+
+// readPool devuelve el pool al que enviar una lectura: la réplica si está configurada y
+// responde, si no el primario
+func readPool() *sql.DB {
+	if readDB != db && !replicaHealthy.Load() {
+		return db
+	}
+	return readDB
+}
+
+// isConnectionError indica si err es un fallo de conexión y no un error de la consulta
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr)
+}
+
+// queryDB ejecuta una consulta que devuelve filas midiendo su duración
+func queryDB(ctx context.Context, name, query string, args ...any) (*sql.Rows, error) {
+	defer observeQuery(ctx, name, time.Now())
+	return db.QueryContext(ctx, query, args...)
+}
+
+// queryRowDB ejecuta una consulta de una sola fila midiendo su duración
+func queryRowDB(ctx context.Context, name, query string, args ...any) *sql.Row {
+	defer observeQuery(ctx, name, time.Now())
+	return db.QueryRowContext(ctx, query, args...)
+}
+
+// execDB ejecuta una sentencia sin filas de resultado midiendo su duración
+func execDB(ctx context.Context, name, query string, args ...any) (sql.Result, error) {
+	defer observeQuery(ctx, name, time.Now())
+	return db.ExecContext(ctx, query, args...)
+}
+
+// queryRowTx es queryRowDB dentro de una transacción de base de datos
+func queryRowTx(ctx context.Context, tx *sql.Tx, name, query string, args ...any) *sql.Row {
+	defer observeQuery(ctx, name, time.Now())
+	return tx.QueryRowContext(ctx, query, args...)
+}
+
+// execTx es execDB dentro de una transacción de base de datos
+func execTx(ctx context.Context, tx *sql.Tx, name, query string, args ...any) (sql.Result, error) {
+	defer observeQuery(ctx, name, time.Now())
+	return tx.ExecContext(ctx, query, args...)
+}
+
+// queryReadDB es queryDB sobre el pool de lectura. Solo para consultas que toleran el
+// retraso de replicación (no para releer algo recién escrito). Si la réplica falla por un
+// error de conexión la consulta se repite en el primario.
+func queryReadDB(ctx context.Context, name, query string, args ...any) (*sql.Rows, error) {
+	defer observeQuery(ctx, name, time.Now())
+	pool := readPool()
+	rows, err := pool.QueryContext(ctx, query, args...)
+	if err != nil && pool != db && isConnectionError(err) {
+		setReplicaHealth(err)
+		return db.QueryContext(ctx, query, args...)
+	}
+	return rows, err
+}
+
+// queryRowReadDB es queryRowDB sobre el pool de lectura. El error de sql.Row solo aparece
+// al hacer Scan, así que aquí no se reintenta: la comprobación periódica retira la réplica.
+func queryRowReadDB(ctx context.Context, name, query string, args ...any) *sql.Row {
+	defer observeQuery(ctx, name, time.Now())
+	return readPool().QueryRowContext(ctx, query, args...)
+}
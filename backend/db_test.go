@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// newMockDB sustituye db y readDB por una base de datos simulada durante el test y
+// comprueba al terminar que se ejecutaron todas las consultas esperadas
+func newMockDB(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevDB, prevReadDB := db, readDB
+	db, readDB = mockDB, mockDB
+	t.Cleanup(func() {
+		db, readDB = prevDB, prevReadDB
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		mockDB.Close()
+	})
+	return mock
+}
+
+// transactionRows devuelve filas con las columnas de transactionColumns
+func transactionRows() *sqlmock.Rows {
+	return sqlmock.NewRows(strings.Split(transactionColumns, ", "))
+}
+
+// addTransactionRow añade una transacción sin los campos opcionales
+func addTransactionRow(rows *sqlmock.Rows, id int, description, amount, typ string) *sqlmock.Rows {
+	return rows.AddRow(transactionRowValues(id, description, amount, typ)...)
+}
+
+// transactionRowValues devuelve los valores de transactionColumns de una transacción sin
+// los campos opcionales, para filas con columnas adicionales detrás
+func transactionRowValues(id int, description, amount, typ string) []driver.Value {
+	created := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	return []driver.Value{id, description, amount, typ, created, defaultSource, false, nil, false, nil, false, nil, statusPosted, nil, created}
+}
+
+// serve ejecuta handler con una petición de prueba y devuelve la respuesta grabada
+func serve(handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(method, target, reader))
+	return rec
+}
+
+// newMockReplica pone una segunda base de datos simulada como réplica de lectura, sana
+// o no según healthy. Debe llamarse después de newMockDB.
+func newMockReplica(t *testing.T, healthy bool) sqlmock.Sqlmock {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevReadDB, prevHealthy := readDB, replicaHealthy.Load()
+	readDB = mockDB
+	replicaHealthy.Store(healthy)
+	t.Cleanup(func() {
+		readDB = prevReadDB
+		replicaHealthy.Store(prevHealthy)
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		mockDB.Close()
+	})
+	return mock
+}
+
+func TestQueryReadDBFallsBackOnConnectionError(t *testing.T) {
+	primary := newMockDB(t)
+	replica := newMockReplica(t, true)
+	replica.ExpectQuery("SELECT 1").
+		WillReturnError(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
+	primary.ExpectQuery("SELECT 1").
+		WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(1))
+
+	rows, err := queryReadDB(context.Background(), "test", "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if replicaHealthy.Load() {
+		t.Error("la réplica sigue marcada como sana tras un error de conexión")
+	}
+}
+
+func TestQueryReadDBKeepsQueryErrors(t *testing.T) {
+	newMockDB(t)
+	replica := newMockReplica(t, true)
+	replica.ExpectQuery("SELECT 1").WillReturnError(errors.New("syntax error"))
+
+	if _, err := queryReadDB(context.Background(), "test", "SELECT 1"); err == nil {
+		t.Fatal("se esperaba el error de la réplica")
+	}
+	if !replicaHealthy.Load() {
+		t.Error("un error de la consulta no debe retirar la réplica")
+	}
+}
+
+func TestReadPoolSkipsUnhealthyReplica(t *testing.T) {
+	primary := newMockDB(t)
+	newMockReplica(t, false)
+	primary.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(1))
+
+	var n int
+	if err := queryRowReadDB(context.Background(), "test", "SELECT 1").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+}
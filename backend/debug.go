@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strings"
+)
+
+// Clave que protege los endpoints /debug/* (DEBUG_API_KEY). Vacía = endpoints deshabilitados.
+var debugAPIKey = ""
+
+// requireDebugKey exige "Authorization: Bearer <DEBUG_API_KEY>". Si no hay clave
+// configurada el endpoint no existe (404), para no exponerlo por descuido.
+func requireDebugKey(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if debugAPIKey == "" {
+			http.NotFound(w, r)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(debugAPIKey)) != 1 {
+			writeError(w, r, http.StatusUnauthorized, msgUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// DBStats es la parte de sql.DBStats útil para ajustar el pool de conexiones
+type DBStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMs     int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// DebugDB es la respuesta de /debug/db: el pool del primario, si hay réplica de lectura el
+// suyo y si está respondiendo, y el estado del esquema
+type DebugDB struct {
+	DBStats
+	Replica *ReplicaStats `json:"replica,omitempty"`
+	Schema  DebugSchema   `json:"schema"`
+}
+
+// DebugSchema es el estado del esquema: no hay migraciones numeradas, así que en lugar de
+// una versión se dan las columnas esperadas y el resultado de la comprobación de arranque
+type DebugSchema struct {
+	ExpectedColumns []string `json:"expected_columns"`
+	SelfCheck
+}
+
+// ReplicaStats es el estado del pool de la réplica de lectura
+type ReplicaStats struct {
+	Healthy bool `json:"healthy"`
+	DBStats
+}
+
+// poolStats extrae de sql.DBStats los campos de DBStats
+func poolStats(pool *sql.DB) DBStats {
+	s := pool.Stats()
+	return DBStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDurationMs:     s.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+}
+
+// Handler para /debug/db (GET: estado de los pools de conexiones y del esquema)
+func getDebugDB(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r, "GET")
+		return
+	}
+
+	check, err := runSelfCheck(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	columns := make([]string, len(transactionColumnDefs))
+	for i, c := range transactionColumnDefs {
+		columns[i] = c.name
+	}
+
+	resp := DebugDB{
+		DBStats: poolStats(db),
+		Schema:  DebugSchema{ExpectedColumns: columns, SelfCheck: check},
+	}
+	if readDB != db {
+		resp.Replica = &ReplicaStats{Healthy: replicaHealthy.Load(), DBStats: poolStats(readDB)}
+	}
+	writeJSON(w, r, http.StatusOK, resp)
+}
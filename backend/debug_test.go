@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestDebugDBSchema(t *testing.T) {
+	mock := newMockDB(t)
+	for _, table := range selfCheckTables {
+		columns := sqlmock.NewRows([]string{"column_name", "data_type"})
+		for _, c := range transactionColumnDefs {
+			if table == "transactions_archive" && c.name == "raw_source" {
+				continue
+			}
+			columns.AddRow(c.name, expectedDataType(c.sqlType))
+		}
+		mock.ExpectQuery("SELECT column_name, data_type FROM information_schema.columns").
+			WithArgs(table).
+			WillReturnRows(columns)
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM " + table).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	}
+
+	rec := serve(getDebugDB, "GET", "/debug/db", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+	}
+	var got DebugDB
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Schema.ExpectedColumns) != len(transactionColumnDefs) {
+		t.Errorf("expected_columns = %v", got.Schema.ExpectedColumns)
+	}
+	if got.Schema.OK || len(got.Schema.Problems) != 1 {
+		t.Errorf("schema = %+v, se esperaba un problema (raw_source en el archivo)", got.Schema)
+	}
+	if got.Replica != nil {
+		t.Errorf("replica = %+v sin réplica configurada", got.Replica)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import "net/http"
+
+// FieldDescriptor describe un campo de Transaction para interfaces genéricas
+type FieldDescriptor struct {
+	Field    string   `json:"field"`            // nombre del campo en el struct
+	JSON     string   `json:"json"`             // clave JSON
+	Type     string   `json:"type"`             // integer, number, string, boolean, datetime
+	Required bool     `json:"required"`         // obligatorio al crear
+	ReadOnly bool     `json:"read_only"`        // lo asigna el servidor
+	Nullable bool     `json:"nullable"`         // puede ser null
+	Filter   string   `json:"filter,omitempty"` // parámetro de /transactions que filtra por el campo
+	Enum     []string `json:"enum,omitempty"`
+}
+
+// transactionSchema es el descriptor de Transaction. Se mantiene a mano junto al struct:
+// al añadir un campo a Transaction hay que añadirlo también aquí.
+var transactionSchema = []FieldDescriptor{
+	{Field: "ID", JSON: "id", Type: "integer", ReadOnly: true, Filter: "ids,id_gte,id_lte"},
+	{Field: "Description", JSON: "description", Type: "string", Required: true, Filter: "q,missing"},
+	{Field: "Amount", JSON: "amount", Type: "number", Required: true},
+	{Field: "Type", JSON: "type", Type: "string", Required: true, Filter: "type", Enum: []string{"income", "expense"}},
+	{Field: "CreatedAt", JSON: "created_at", Type: "datetime", Filter: "from,to,weekday,last_days"},
+	{Field: "Source", JSON: "source", Type: "string", Filter: "source"},
+	{Field: "Cleared", JSON: "cleared", Type: "boolean", Filter: "cleared"},
+	{Field: "ParentID", JSON: "parent_id", Type: "integer", ReadOnly: true, Nullable: true},
+	{Field: "Split", JSON: "split", Type: "boolean", ReadOnly: true},
+	{Field: "Payee", JSON: "payee", Type: "string", Nullable: true, Filter: "payee,missing"},
+	{Field: "IsTemplate", JSON: "is_template", Type: "boolean"},
+	{Field: "UUID", JSON: "uuid", Type: "string", Nullable: true},
+	{Field: "Status", JSON: "status", Type: "string", Filter: "status,include_pending", Enum: []string{"pending", "posted"}},
+	{Field: "Reference", JSON: "reference", Type: "string", Nullable: true, Filter: "reference"},
+	{Field: "UpdatedAt", JSON: "updated_at", Type: "datetime", ReadOnly: true},
+	{Field: "FormattedAmount", JSON: "formatted_amount", Type: "string", ReadOnly: true},
+	{Field: "RawSource", JSON: "raw_source", Type: "string", ReadOnly: true, Nullable: true},
+}
+
+// Handler para /schema/transaction (GET: campos de una transacción, sus tipos y filtros)
+func getTransactionSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r, "GET")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, transactionSchema)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// schemaType traduce el tipo Go de un campo de Transaction al tipo de FieldDescriptor
+func schemaType(t reflect.Type) string {
+	switch t {
+	case reflect.TypeOf(Amount{}):
+		return "number"
+	case reflect.TypeOf(time.Time{}):
+		return "datetime"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64:
+		return "integer"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	}
+	return t.String()
+}
+
+// transactionSchema se mantiene a mano: este test falla si deja de coincidir con el struct
+func TestTransactionSchemaMatchesStruct(t *testing.T) {
+	typ := reflect.TypeOf(Transaction{})
+	if typ.NumField() != len(transactionSchema) {
+		t.Fatalf("Transaction tiene %d campos y transactionSchema %d", typ.NumField(), len(transactionSchema))
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field, desc := typ.Field(i), transactionSchema[i]
+		if field.Name != desc.Field {
+			t.Errorf("posición %d: campo %s, el esquema dice %s", i, field.Name, desc.Field)
+			continue
+		}
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonName != desc.JSON {
+			t.Errorf("%s: clave JSON %q, el esquema dice %q", field.Name, jsonName, desc.JSON)
+		}
+		fieldType := field.Type
+		nullable := fieldType.Kind() == reflect.Pointer
+		if nullable {
+			fieldType = fieldType.Elem()
+		}
+		if nullable != desc.Nullable {
+			t.Errorf("%s: nullable %t, el esquema dice %t", field.Name, nullable, desc.Nullable)
+		}
+		if got := schemaType(fieldType); got != desc.Type {
+			t.Errorf("%s: tipo %s, el esquema dice %s", field.Name, got, desc.Type)
+		}
+	}
+}
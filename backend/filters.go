@@ -0,0 +1,271 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Condición que deja fuera de listas y resúmenes las filas que no son movimientos reales:
+// las transacciones originales que se dividieron (sus partes ya las representan) y las plantillas
+const visibleTransactions = "NOT is_split AND NOT is_template"
+
+// sqlFilter acumula condiciones WHERE con sus argumentos posicionales ($1, $2, ...)
+type sqlFilter struct {
+	conds []string
+	args  []any
+	base  int // número de condiciones fijas, que no vienen de los parámetros
+}
+
+// filtered indica si la petición aportó algún filtro además de las condiciones fijas
+func (f *sqlFilter) filtered() bool {
+	return len(f.conds) > f.base
+}
+
+// arg registra un argumento y devuelve su marcador posicional
+func (f *sqlFilter) arg(v any) string {
+	f.args = append(f.args, v)
+	return "$" + strconv.Itoa(len(f.args))
+}
+
+func (f *sqlFilter) add(cond string) {
+	f.conds = append(f.conds, cond)
+}
+
+// where devuelve la cláusula WHERE completa, o "" si no hay condiciones
+func (f *sqlFilter) where() string {
+	if len(f.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(f.conds, " AND ")
+}
+
+// Parámetros de filtro de un solo valor. q.Get se queda con el primero, así que
+// ?type=income&type=expense se rechaza en lugar de ignorar en silencio el segundo; las
+// listas se pasan separadas por comas (?source=banco,manual).
+var singleValuedFilterParams = []string{
+	"q", "source", "cleared", "payee", "type", "status", "include_pending", "missing",
+	"weekday", "id_gte", "id_lte", "from", "to", "last_days", "reference", "ids",
+}
+
+// Máximo de ?last_days= (unos diez años)
+const maxLastDays = 3650
+
+// checkSingleValued devuelve un error si alguno de los parámetros aparece más de una vez
+func checkSingleValued(q url.Values, names ...string) error {
+	for _, name := range names {
+		if len(q[name]) > 1 {
+			return newAPIError(msgDuplicateParam, name)
+		}
+	}
+	return nil
+}
+
+// buildTransactionFilter traduce los parámetros de consulta de la lista de transacciones
+// a condiciones SQL. Se comparte entre los endpoints que filtran transacciones.
+func buildTransactionFilter(q url.Values) (*sqlFilter, error) {
+	if err := checkSingleValued(q, singleValuedFilterParams...); err != nil {
+		return nil, err
+	}
+	f := &sqlFilter{conds: []string{visibleTransactions}, base: 1}
+	if search := strings.TrimSpace(q.Get("q")); search != "" {
+		f.add("description ILIKE " + f.arg("%"+escapeLike(search)+"%"))
+	}
+	if sources := splitList(q.Get("source")); len(sources) > 0 {
+		f.add("source = ANY(" + f.arg(pq.Array(sources)) + ")")
+	}
+	if raw := q.Get("cleared"); raw != "" {
+		cleared, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, newAPIError(msgInvalidClearedFilter)
+		}
+		f.add("cleared = " + f.arg(cleared))
+	}
+	if payee := normalizePayee(ptr(q.Get("payee"))); payee != nil {
+		f.add("lower(payee) = lower(" + f.arg(*payee) + ")")
+	}
+	if raw := q.Get("type"); raw != "" {
+		if !isValidType(raw) {
+			return nil, newAPIError(msgInvalidTypeFilter)
+		}
+		f.add("type = " + f.arg(raw))
+	}
+	if ref := normalizeReference(ptr(q.Get("reference"))); ref != nil {
+		f.add("reference = " + f.arg(*ref))
+	}
+	if raw := q.Get("status"); raw != "" {
+		if !isValidStatus(raw) {
+			return nil, newAPIError(msgInvalidStatus)
+		}
+		f.add("status = " + f.arg(raw))
+	}
+	// ?include_pending=false deja fuera las pendientes, p. ej. para un balance solo con lo asentado
+	if raw := q.Get("include_pending"); raw != "" {
+		include, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, newAPIError(msgInvalidIncludePending)
+		}
+		if !include {
+			f.add("status <> " + f.arg(statusPending))
+		}
+	}
+	if raw := q.Get("missing"); raw != "" {
+		cond, err := missingFieldsCondition(raw)
+		if err != nil {
+			return nil, err
+		}
+		f.add(cond)
+	}
+	if raw := q.Get("weekday"); raw != "" {
+		days, err := parseWeekdays(raw)
+		if err != nil {
+			return nil, err
+		}
+		// El día de la semana se calcula en la zona horaria de la aplicación, no en UTC
+		f.add("EXTRACT(DOW FROM created_at AT TIME ZONE " + f.arg(appLocation.String()) + ")::int = ANY(" + f.arg(pq.Array(days)) + ")")
+	}
+	idGTE, err := parseIDParam(q, "id_gte")
+	if err != nil {
+		return nil, err
+	}
+	idLTE, err := parseIDParam(q, "id_lte")
+	if err != nil {
+		return nil, err
+	}
+	if idGTE > 0 && idLTE > 0 && idGTE > idLTE {
+		return nil, newAPIError(msgIDRangeInverted)
+	}
+	if idGTE > 0 {
+		f.add("id >= " + f.arg(idGTE))
+	}
+	if idLTE > 0 {
+		f.add("id <= " + f.arg(idLTE))
+	}
+	from, err := parseDateParam(q, "from")
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseDateParam(q, "to")
+	if err != nil {
+		return nil, err
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return nil, newAPIError(msgFromAfterTo)
+	}
+	// Los días empiezan a medianoche en la zona horaria de la aplicación, igual que en
+	// last_days, y no en la de la sesión de PostgreSQL
+	if !from.IsZero() {
+		f.add("created_at >= (" + f.arg(from.Format(dateLayout)) + "::date)::timestamp AT TIME ZONE " + f.arg(appLocation.String()))
+	}
+	if !to.IsZero() {
+		// 'to' es inclusivo: abarca el día completo
+		f.add("created_at < (" + f.arg(to.Format(dateLayout)) + "::date + 1)::timestamp AT TIME ZONE " + f.arg(appLocation.String()))
+	}
+	if raw := q.Get("last_days"); raw != "" {
+		if !from.IsZero() || !to.IsZero() {
+			return nil, newAPIError(msgLastDaysWithRange)
+		}
+		days, err := strconv.Atoi(raw)
+		if err != nil || days < 1 || days > maxLastDays {
+			return nil, newAPIError(msgInvalidLastDays, maxLastDays)
+		}
+		f.add("created_at >= " + f.arg(lastDaysStart(days)))
+	}
+	return f, nil
+}
+
+// lastDaysStart devuelve el inicio de ?last_days=n: la medianoche, en la zona horaria de la
+// aplicación, de n-1 días antes de hoy. last_days=1 es solo hoy y last_days=7 la última semana.
+func lastDaysStart(n int) time.Time {
+	now := appNow()
+	return time.Date(now.Year(), now.Month(), now.Day()-(n-1), 0, 0, 0, 0, appLocation)
+}
+
+// Campos aceptados en ?missing= y la condición que los considera vacíos
+var missingFieldConditions = map[string]string{
+	"description": "btrim(description) = ''",
+	"payee":       "(payee IS NULL OR btrim(payee) = '')",
+}
+
+// missingFieldsCondition convierte "description,payee" en una condición que encuentra las
+// filas a las que les falta alguno de esos campos
+func missingFieldsCondition(raw string) (string, error) {
+	var conds []string
+	for _, field := range splitList(strings.ToLower(raw)) {
+		cond, ok := missingFieldConditions[field]
+		if !ok {
+			return "", newAPIError(msgInvalidMissingField, field)
+		}
+		conds = append(conds, cond)
+	}
+	if len(conds) == 0 {
+		return "", newAPIError(msgInvalidMissingField, raw)
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", nil
+}
+
+// Días de la semana aceptados en ?weekday=, con su número DOW de Postgres (domingo = 0)
+var weekdayNumbers = map[string]int64{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// parseWeekdays convierte "sat,sun" en los números DOW correspondientes
+func parseWeekdays(raw string) ([]int64, error) {
+	var days []int64
+	for _, token := range splitList(strings.ToLower(raw)) {
+		day, ok := weekdayNumbers[token]
+		if !ok {
+			return nil, newAPIError(msgInvalidWeekday, token)
+		}
+		days = append(days, day)
+	}
+	if len(days) == 0 {
+		return nil, newAPIError(msgInvalidWeekday, raw)
+	}
+	return days, nil
+}
+
+// parseIDParam lee un parámetro con un id entero positivo; devuelve 0 si no viene
+func parseIDParam(q url.Values, name string) (int64, error) {
+	raw := q.Get(name)
+	if raw == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, newAPIError(msgInvalidIDParam, name)
+	}
+	return id, nil
+}
+
+// parseDateParam lee un parámetro de fecha YYYY-MM-DD; devuelve el valor cero si no viene
+func parseDateParam(q url.Values, name string) (time.Time, error) {
+	raw := q.Get(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	d, err := time.Parse(dateLayout, raw)
+	if err != nil {
+		return time.Time{}, newAPIError(msgInvalidDate, name)
+	}
+	return d, nil
+}
+
+// splitList separa un parámetro "a,b,c" ignorando los elementos vacíos
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// escapeLike escapa los comodines de LIKE para buscar el texto literalmente
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// apiErrorKey devuelve la clave del mensaje de un error de la API, o "" si no lo es
+func apiErrorKey(err error) msgKey {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.key
+	}
+	return ""
+}
+
+func TestBuildTransactionFilterIDBounds(t *testing.T) {
+	cases := []struct {
+		query     string
+		wantConds []string
+		wantArgs  []any
+		wantErr   msgKey
+	}{
+		{query: "id_gte=5", wantConds: []string{"id >= $1"}, wantArgs: []any{int64(5)}},
+		{query: "id_lte=9", wantConds: []string{"id <= $1"}, wantArgs: []any{int64(9)}},
+		{query: "id_gte=5&id_lte=9", wantConds: []string{"id >= $1", "id <= $2"}, wantArgs: []any{int64(5), int64(9)}},
+		{query: "id_gte=7&id_lte=7", wantConds: []string{"id >= $1", "id <= $2"}, wantArgs: []any{int64(7), int64(7)}},
+		{query: "id_gte=9&id_lte=5", wantErr: msgIDRangeInverted},
+		{query: "id_gte=0", wantErr: msgInvalidIDParam},
+		{query: "id_lte=abc", wantErr: msgInvalidIDParam},
+	}
+	for _, c := range cases {
+		q, _ := url.ParseQuery(c.query)
+		f, err := buildTransactionFilter(q)
+		if c.wantErr != "" {
+			if key := apiErrorKey(err); key != c.wantErr {
+				t.Errorf("%s: error %v, se esperaba %s", c.query, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: error inesperado %v", c.query, err)
+			continue
+		}
+		if got := f.conds[f.base:]; !reflect.DeepEqual(got, c.wantConds) {
+			t.Errorf("%s: condiciones %q, se esperaba %q", c.query, got, c.wantConds)
+		}
+		if !reflect.DeepEqual(f.args, c.wantArgs) {
+			t.Errorf("%s: argumentos %v, se esperaba %v", c.query, f.args, c.wantArgs)
+		}
+	}
+}
+
+func TestRepeatedFilterParams(t *testing.T) {
+	for _, query := range []string{"type=income&type=expense", "ids=1&ids=2", "id_gte=1&id_gte=2"} {
+		q, _ := url.ParseQuery(query)
+		if _, err := buildTransactionFilter(q); apiErrorKey(err) != msgDuplicateParam {
+			t.Errorf("%s: error %v, se esperaba %s", query, err, msgDuplicateParam)
+		}
+	}
+
+	// La lista por ids no usa buildTransactionFilter, pero también rechaza ?ids= repetido
+	newMockDB(t)
+	rec := serve(handleTransactions, "GET", "/transactions?ids=1&ids=2", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("ids repetido: estado %d, se esperaba 400", rec.Code)
+	}
+}
+
+func TestBuildTransactionFilterDateRangeTimeZone(t *testing.T) {
+	defer func() { appLocation = time.UTC }()
+	appLocation = time.FixedZone("Europe/Madrid", 2*3600)
+
+	q, _ := url.ParseQuery("from=2024-03-01&to=2024-03-31")
+	f, err := buildTransactionFilter(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantConds := []string{
+		"created_at >= ($1::date)::timestamp AT TIME ZONE $2",
+		"created_at < ($3::date + 1)::timestamp AT TIME ZONE $4",
+	}
+	wantArgs := []any{"2024-03-01", "Europe/Madrid", "2024-03-31", "Europe/Madrid"}
+	if got := f.conds[f.base:]; !reflect.DeepEqual(got, wantConds) {
+		t.Errorf("condiciones %q, se esperaba %q", got, wantConds)
+	}
+	if !reflect.DeepEqual(f.args, wantArgs) {
+		t.Errorf("argumentos %v, se esperaba %v", f.args, wantArgs)
+	}
+}
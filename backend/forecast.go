@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Meses de histórico usados para la previsión y máximo de meses a proyectar
+const (
+	forecastHistoryMonths = 3
+	maxForecastMonths     = 12
+)
+
+// ForecastMonth es la proyección de un mes futuro
+type ForecastMonth struct {
+	Month   string `json:"month"` // formato YYYY-MM
+	Income  Amount `json:"income"`
+	Expense Amount `json:"expense"`
+	Net     Amount `json:"net"`
+}
+
+// Handler para /forecast (GET: previsión de ingresos/gastos para los próximos ?months= meses).
+//
+// Método: se toma la media mensual de ingresos y de gastos de los últimos 3 meses
+// completos (el mes en curso no cuenta, porque está incompleto) y se proyecta esa
+// misma media para cada mes futuro, empezando por el siguiente al actual. Los meses
+// sin transacciones dentro de la ventana cuentan como 0, así que un histórico corto
+// reduce la media. No hay transacciones recurrentes que sumar en este modelo.
+func getForecast(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r, "GET")
+		return
+	}
+
+	months := 3
+	if raw := r.URL.Query().Get("months"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxForecastMonths {
+			writeError(w, r, http.StatusBadRequest, msgInvalidMonths, maxForecastMonths)
+			return
+		}
+		months = parsed
+	}
+
+	// La ventana son los meses completos anteriores al actual en la zona horaria de la
+	// aplicación, no en la de la sesión de PostgreSQL
+	now := appNow()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	historyFrom := start.AddDate(0, -forecastHistoryMonths, 0)
+
+	var income, expense Amount
+	err := queryRowReadDB(r.Context(), "forecast_history", `
+		SELECT COALESCE(SUM(CASE WHEN type = 'income' THEN `+magnitudeSQL()+` END), 0),
+		       COALESCE(SUM(CASE WHEN type = 'expense' THEN `+magnitudeSQL()+` END), 0)
+		FROM transactions
+		WHERE `+visibleTransactions+`
+		  AND created_at >= $1
+		  AND created_at < $2`, historyFrom.UTC(), start.UTC()).Scan(&income, &expense)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	history := decimal.NewFromInt(forecastHistoryMonths)
+	avgIncome := roundCents(Amount{income.Div(history)})
+	avgExpense := roundCents(Amount{expense.Div(history)})
+
+	forecast := make([]ForecastMonth, 0, months)
+	for i := 1; i <= months; i++ {
+		forecast = append(forecast, ForecastMonth{
+			Month:   start.AddDate(0, i, 0).Format("2006-01"),
+			Income:  avgIncome,
+			Expense: avgExpense,
+			Net:     avgIncome.Sub(avgExpense),
+		})
+	}
+
+	writeJSON(w, r, http.StatusOK, forecast)
+}
+
+// roundCents redondea un importe a céntimos con el modo de redondeo de los resúmenes
+func roundCents(a Amount) Amount {
+	return Amount{roundDecimal(a.Decimal, 2, summaryRounding)}
+}
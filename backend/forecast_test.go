@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestForecastWindowInAppTimezone(t *testing.T) {
+	defer func() { appLocation = time.UTC }()
+	appLocation = time.FixedZone("Asia/Tokyo", 9*3600)
+
+	now := appNow()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, appLocation)
+	mock := newMockDB(t)
+	mock.ExpectQuery("SELECT .+ FROM transactions").
+		WithArgs(appTimeArg{start.AddDate(0, -forecastHistoryMonths, 0), 0}, appTimeArg{start, 0}).
+		WillReturnRows(sqlmock.NewRows([]string{"income", "expense"}).AddRow("300.00", "150.00"))
+
+	rec := serve(getForecast, "GET", "/forecast?months=1", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+	}
+	var got []ForecastMonth
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Month != start.AddDate(0, 1, 0).Format("2006-01") || got[0].Net.String() != "50.00" {
+		t.Errorf("previsión = %+v", got)
+	}
+}
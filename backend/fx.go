@@ -0,0 +1,76 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Tipos de cambio estáticos desde DEFAULT_CURRENCY (FX_RATES="EUR=0.92,GBP=0.79": unidades de
+// cada moneda por 1 unidad de la moneda por defecto). Se fijan al arrancar; para tipos al día
+// basta con sustituir fxRate por una función que consulte un proveedor externo.
+var fxRates = map[string]decimal.Decimal{}
+
+// fxRate devuelve el tipo de cambio de from a to. Es una variable para poder enchufar otra
+// fuente de tipos; por defecto usa FX_RATES.
+var fxRate = staticFXRate
+
+// staticFXRate busca el tipo en FX_RATES. Solo conoce tipos desde la moneda por defecto,
+// que es la única en que se guardan las transacciones.
+func staticFXRate(from, to string) (decimal.Decimal, bool) {
+	if from == to {
+		return decimal.NewFromInt(1), true
+	}
+	if from != defaultCurrency {
+		return decimal.Decimal{}, false
+	}
+	rate, ok := fxRates[to]
+	return rate, ok
+}
+
+// loadFXRates lee FX_RATES; un par mal formado detiene el arranque
+func loadFXRates() {
+	v := os.Getenv("FX_RATES")
+	if v == "" {
+		return
+	}
+	for _, pair := range splitList(v) {
+		code, raw, ok := strings.Cut(pair, "=")
+		code = strings.ToUpper(strings.TrimSpace(code))
+		rate, err := decimal.NewFromString(strings.TrimSpace(raw))
+		if !ok || len(code) != 3 || err != nil || !rate.IsPositive() {
+			log.Fatalf("FX_RATES inválido: %q (usa pares como EUR=0.92,GBP=0.79)", pair)
+		}
+		fxRates[code] = rate
+	}
+}
+
+// parseConvertTo lee ?convert_to= y devuelve la moneda de destino y el tipo de cambio desde
+// la moneda por defecto. Sin el parámetro devuelve la moneda por defecto y un tipo 1.
+func parseConvertTo(r *http.Request) (string, decimal.Decimal, error) {
+	raw := strings.TrimSpace(r.URL.Query().Get("convert_to"))
+	if raw == "" {
+		return defaultCurrency, decimal.NewFromInt(1), nil
+	}
+	currency := strings.ToUpper(raw)
+	if len(currency) != 3 {
+		return "", decimal.Decimal{}, newAPIError(msgInvalidConvertTo)
+	}
+	rate, ok := fxRate(defaultCurrency, currency)
+	if !ok {
+		return "", decimal.Decimal{}, newAPIError(msgMissingFXRate, defaultCurrency, currency)
+	}
+	return currency, rate, nil
+}
+
+// convertedAmountSQL es magnitudeSQL convertido con rate. Cada importe se convierte y se
+// redondea a céntimos antes de sumarse, como si se hubiera registrado en esa moneda.
+func convertedAmountSQL(f *sqlFilter, rate decimal.Decimal) string {
+	if rate.Equal(decimal.NewFromInt(1)) {
+		return magnitudeSQL()
+	}
+	return "ROUND(" + magnitudeSQL() + " * " + f.arg(rate.String()) + "::numeric, 2)"
+}
@@ -2,4 +2,9 @@ module transaction-app/backend
 
 go 1.25.1
 
-require github.com/lib/pq v1.10.9
+require (
+	github.com/DATA-DOG/go-sqlmock v1.5.2
+	github.com/jung-kurt/gofpdf v1.16.2
+	github.com/lib/pq v1.10.9
+	github.com/shopspring/decimal v1.4.0
+)
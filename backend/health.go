@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Tiempo máximo que /readyz espera al ping de la base de datos (HEALTH_DB_TIMEOUT)
+var healthDBTimeout = 2 * time.Second
+
+// Handler para /healthz (GET: el proceso está vivo; no consulta la base de datos)
+func getHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// Handler para /readyz (GET: el servidor puede atender peticiones porque la base de datos
+// responde). El ping tiene un plazo de HEALTH_DB_TIMEOUT para que una base de datos lenta
+// haga fallar la sonda enseguida en lugar de dejarla colgada.
+func getReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthDBTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		writeError(w, r, http.StatusServiceUnavailable, msgNotReady, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Match indica una coincidencia de búsqueda dentro de la descripción.
+// Start y Length se expresan en caracteres (runas), no en bytes.
+type Match struct {
+	Start  int `json:"start"`
+	Length int `json:"length"`
+}
+
+// HighlightedTransaction es una transacción acompañada de las coincidencias de ?q=
+type HighlightedTransaction struct {
+	Transaction
+	Highlights []Match `json:"highlights"`
+}
+
+// findMatches localiza todas las apariciones (sin solapamiento) de query en text,
+// sin distinguir mayúsculas de minúsculas
+func findMatches(text, query string) []Match {
+	matches := []Match{}
+	needle := lowerRunes(strings.TrimSpace(query))
+	if len(needle) == 0 {
+		return matches
+	}
+	haystack := lowerRunes(text)
+	for i := 0; i+len(needle) <= len(haystack); {
+		if string(haystack[i:i+len(needle)]) == string(needle) {
+			matches = append(matches, Match{Start: i, Length: len(needle)})
+			i += len(needle)
+			continue
+		}
+		i++
+	}
+	return matches
+}
+
+// lowerRunes pasa a minúsculas carácter a carácter, conservando la longitud en runas
+func lowerRunes(s string) []rune {
+	runes := []rune(s)
+	for i := range runes {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return runes
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Tipo de contenido de JSON:API (https://jsonapi.org)
+const jsonAPIMediaType = "application/vnd.api+json"
+
+// jsonAPIResource es un objeto recurso de JSON:API
+type jsonAPIResource struct {
+	Type       string                     `json:"type"`
+	ID         string                     `json:"id"`
+	Attributes map[string]json.RawMessage `json:"attributes"`
+	Meta       map[string]any             `json:"meta,omitempty"` // datos que no son del recurso, como las coincidencias de ?highlight
+}
+
+// wantsJSONAPI indica si el cliente pidió JSON:API en la cabecera Accept
+func wantsJSONAPI(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), jsonAPIMediaType)
+}
+
+// transactionResource convierte una transacción en un objeto recurso. Los atributos salen
+// de su serialización normal (mismas claves y formato de importes) sin el id.
+func transactionResource(t Transaction) (jsonAPIResource, error) {
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return jsonAPIResource{}, err
+	}
+	var attrs map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &attrs); err != nil {
+		return jsonAPIResource{}, err
+	}
+	delete(attrs, "id")
+	return jsonAPIResource{Type: "transactions", ID: strconv.Itoa(t.ID), Attributes: attrs}, nil
+}
+
+// writeTransactions escribe una lista de transacciones en formato plano o, si el cliente
+// lo pidió con Accept, como documento JSON:API {"data": [...]}. Con ?with_symbol=true
+// añade formatted_amount.
+func writeTransactions(w http.ResponseWriter, r *http.Request, status int, transactions []Transaction) {
+	writeTransactionList(w, r, status, transactions, "", false)
+}
+
+// writeHighlightedTransactions es writeTransactions con las coincidencias de search en la
+// descripción: en formato plano van en "highlights" junto a cada transacción y en JSON:API
+// en el meta de cada recurso
+func writeHighlightedTransactions(w http.ResponseWriter, r *http.Request, status int, transactions []Transaction, search string) {
+	writeTransactionList(w, r, status, transactions, search, true)
+}
+
+func writeTransactionList(w http.ResponseWriter, r *http.Request, status int, transactions []Transaction, search string, highlight bool) {
+	if wantsSymbol(r) {
+		addFormattedAmounts(transactions)
+	}
+	if !wantsJSONAPI(r) {
+		if !highlight {
+			writeJSON(w, r, status, transactions)
+			return
+		}
+		highlighted := make([]HighlightedTransaction, 0, len(transactions))
+		for _, t := range transactions {
+			highlighted = append(highlighted, HighlightedTransaction{Transaction: t, Highlights: findMatches(t.Description, search)})
+		}
+		writeJSON(w, r, status, highlighted)
+		return
+	}
+	data := make([]jsonAPIResource, 0, len(transactions))
+	for _, t := range transactions {
+		res, err := transactionResource(t)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if highlight {
+			res.Meta = map[string]any{"highlights": findMatches(t.Description, search)}
+		}
+		data = append(data, res)
+	}
+	writeJSONAPI(w, r, status, data)
+}
+
+// writeTransaction es writeTransactions para un único recurso ({"data": {...}})
+func writeTransaction(w http.ResponseWriter, r *http.Request, status int, t Transaction) {
+	if wantsSymbol(r) {
+		t.FormattedAmount = formatAmount(t.Amount, defaultCurrency)
+	}
+	if !wantsJSONAPI(r) {
+		writeJSON(w, r, status, t)
+		return
+	}
+	res, err := transactionResource(t)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSONAPI(w, r, status, res)
+}
+
+// writeJSONAPI envuelve data en un documento JSON:API con su tipo de contenido
+func writeJSONAPI(w http.ResponseWriter, r *http.Request, status int, data any) {
+	w.Header().Set("Content-Type", jsonAPIMediaType)
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	if wantsPretty(r) {
+		enc.SetIndent("", "  ")
+	}
+	enc.Encode(map[string]any{"data": data})
+}
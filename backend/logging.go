@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Registrar el cuerpo de las peticiones de escritura (DEBUG_LOG_BODIES=true), solo para depurar
+var debugLogBodies = false
+
+// Bytes máximos del cuerpo que se registran (DEBUG_LOG_BODY_MAX)
+var debugLogBodyMax = 2048
+
+// statusRecorder guarda el código de estado que escribe el handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap da acceso al ResponseWriter original (http.ResponseController lo usa para Flush)
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// loggingHandler registra cada petición con su estado, duración e id. Con DEBUG_LOG_BODIES
+// también registra (recortado) el cuerpo de las escrituras.
+func loggingHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		if debugLogBodies && isWriteMethod(r.Method) {
+			logRequestBody(r)
+		}
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+		log.Printf("%s %s %d %s request_id=%s",
+			r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Microsecond), requestIDFromContext(r.Context()))
+	})
+}
+
+// logRequestBody registra los primeros debugLogBodyMax bytes del cuerpo y los devuelve
+// delante del resto, de modo que el handler sigue leyendo el cuerpo completo
+func logRequestBody(r *http.Request) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return
+	}
+	// Si la lectura falla, el handler se encontrará el mismo error al seguir leyendo
+	prefix, _ := io.ReadAll(io.LimitReader(r.Body, int64(debugLogBodyMax)+1))
+	truncated := len(prefix) > debugLogBodyMax
+	logged := prefix
+	if truncated {
+		logged = prefix[:debugLogBodyMax]
+	}
+	log.Printf("cuerpo de %s %s request_id=%s truncado=%t: %s",
+		r.Method, r.URL.Path, requestIDFromContext(r.Context()), truncated, logged)
+
+	body := r.Body
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), body), body}
+}
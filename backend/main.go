@@ -1,8 +1,9 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,20 +12,124 @@ import (
 	"strings"
 	"time"
 
-	_ "github.com/lib/pq" // Driver para PostgreSQL
+	"github.com/lib/pq" // Driver para PostgreSQL
 )
 
 // Transaction representa una transacción de dinero
 type Transaction struct {
-	ID          int       `json:"id"`
-	Description string    `json:"description"`
-	Amount      float64   `json:"amount"`
-	Type        string    `json:"type"` // "income" o "expense"
-	CreatedAt   time.Time `json:"created_at"`
+	ID              int       `json:"id"`
+	Description     string    `json:"description"`
+	Amount          Amount    `json:"amount"`
+	Type            string    `json:"type"` // "income" o "expense"
+	CreatedAt       time.Time `json:"created_at"`
+	Source          string    `json:"source"`                     // origen del dato: "manual" por defecto
+	Cleared         bool      `json:"cleared"`                    // conciliada con el extracto bancario
+	ParentID        *int      `json:"parent_id"`                  // transacción original si es una parte de una división
+	Split           bool      `json:"split"`                      // true si se dividió en partes (ya no cuenta en listas ni resúmenes)
+	Payee           *string   `json:"payee"`                      // beneficiario o contraparte (opcional)
+	IsTemplate      bool      `json:"is_template"`                // plantilla para entrada rápida (no cuenta en listas ni resúmenes)
+	UUID            *string   `json:"uuid"`                       // identificador generado por el cliente para reintentar creaciones (opcional)
+	Status          string    `json:"status"`                     // "posted" (por defecto) o "pending" si el banco aún no la ha asentado
+	Reference       *string   `json:"reference"`                  // referencia externa, como un número de factura (opcional, única)
+	UpdatedAt       time.Time `json:"updated_at"`                 // última modificación, según el reloj de la base de datos
+	FormattedAmount string    `json:"formatted_amount,omitempty"` // importe con símbolo ("$19.99"), solo con ?with_symbol=true; no se guarda
+	RawSource       *string   `json:"raw_source,omitempty"`       // línea original del CSV si se importó, solo con ?include_raw=true
 }
 
 var db *sql.DB
 
+// Columnas seleccionadas al leer transacciones, en el orden que espera scanTransaction
+const transactionColumns = "id, description, amount, type, created_at, source, cleared, parent_id, is_split, payee, is_template, uuid, status, reference, updated_at"
+
+// selectTransactionColumns devuelve transactionColumns y, con withRaw, también raw_source.
+// La fila original solo se lee cuando se pide, para no inflar las respuestas.
+func selectTransactionColumns(withRaw bool) string {
+	if withRaw {
+		return transactionColumns + ", raw_source"
+	}
+	return transactionColumns
+}
+
+// includeRawSource indica si la petición pide la línea original de las importadas (?include_raw=true)
+func includeRawSource(r *http.Request) bool {
+	return r.URL.Query().Get("include_raw") == "true"
+}
+
+// rowScanner abstrae *sql.Row y *sql.Rows para compartir el escaneo
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTransaction(s rowScanner, t *Transaction) error {
+	if err := s.Scan(transactionScanDest(t)...); err != nil {
+		return err
+	}
+	apiAmount(t)
+	return nil
+}
+
+// scanSelectedTransaction escanea una fila de selectTransactionColumns(withRaw)
+func scanSelectedTransaction(s rowScanner, t *Transaction, withRaw bool) error {
+	dest := transactionScanDest(t)
+	if withRaw {
+		dest = append(dest, &t.RawSource)
+	}
+	if err := s.Scan(dest...); err != nil {
+		return err
+	}
+	apiAmount(t)
+	return nil
+}
+
+// transactionScanDest devuelve los destinos de Scan de transactionColumns, para consultas
+// que seleccionan columnas adicionales detrás de las de la transacción
+func transactionScanDest(t *Transaction) []any {
+	return []any{&t.ID, &t.Description, &t.Amount, &t.Type, &t.CreatedAt, &t.Source, &t.Cleared, &t.ParentID, &t.Split, &t.Payee, &t.IsTemplate, &t.UUID, &t.Status, &t.Reference, &t.UpdatedAt}
+}
+
+// Tipo asignado al crear una transacción sin "type" (DEFAULT_TRANSACTION_TYPE).
+// Vacío significa que el tipo es obligatorio.
+var defaultTransactionType = ""
+
+// isValidType indica si el tipo de transacción es uno de los admitidos
+func isValidType(t string) bool {
+	return t == "income" || t == "expense"
+}
+
+// inferTypeFromSign asigna el tipo según el signo del importe y guarda su valor absoluto.
+// Un tipo explícito que contradiga el signo se rechaza, igual que un importe 0. Con
+// ALLOW_ZERO_AMOUNT el 0 se admite si viene con tipo, porque no tiene signo del que deducirlo.
+func inferTypeFromSign(t *Transaction) error {
+	if t.Amount.IsZero() {
+		if allowZeroAmount && t.Type != "" {
+			return nil
+		}
+		return newAPIError(msgZeroAmount)
+	}
+	inferred := "income"
+	if t.Amount.IsNegative() {
+		inferred = "expense"
+		t.Amount = t.Amount.Neg()
+	}
+	if t.Type != "" && t.Type != inferred {
+		return newAPIError(msgTypeSignMismatch, t.Type)
+	}
+	t.Type = inferred
+	return nil
+}
+
+// Origen asignado a las transacciones creadas sin indicar source
+const defaultSource = "manual"
+
+// Máximo de ids aceptados en /transactions?ids=
+const maxIDsPerRequest = 100
+
+// Tamaño por defecto y máximo de /transactions/recent
+const (
+	defaultRecentCount = 10
+	maxRecentCount     = 50
+)
+
 func main() {
 	// Obtener variables de entorno
 	dbHost := os.Getenv("DB_HOST")
@@ -33,6 +138,7 @@ func main() {
 	dbPassword := os.Getenv("DB_PASSWORD")
 	dbName := os.Getenv("DB_NAME")
 	apiPort := os.Getenv("API_PORT")
+	loadConfig()
 
 	if apiPort == "" {
 		apiPort = "3000" // Puerto por defecto si no se especifica
@@ -62,68 +168,232 @@ func main() {
 	}
 	defer db.Close()
 
-	// Crear la tabla si no existe
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS transactions (
-		id SERIAL PRIMARY KEY,
-		description TEXT NOT NULL,
-		amount NUMERIC(10, 2) NOT NULL,
-		type VARCHAR(10) NOT NULL,
-		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-	);`
-	_, err = db.Exec(createTableSQL)
+	// Réplica de lectura opcional para listas y resúmenes
+	readDB = db
+	if replicaHost := os.Getenv("DB_REPLICA_HOST"); replicaHost != "" {
+		replicaPort := os.Getenv("DB_REPLICA_PORT")
+		if replicaPort == "" {
+			replicaPort = dbPort
+		}
+		openReadReplica(fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+			replicaHost, replicaPort, dbUser, dbPassword, dbName))
+		if readDB != db {
+			defer readDB.Close()
+		}
+	}
+
+	// Crear la tabla si no existe y añadir las columnas nuevas
+	err = ensureSchema()
 	if err != nil {
 		log.Fatalf("Error al crear la tabla de transacciones: %v", err)
 	}
 	log.Println("Tabla 'transactions' verificada/creada.")
+	startupSelfCheck()
 
-	// Configurar CORS (para permitir peticiones desde el frontend)
-	corsHandler := func(h http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Lista de orígenes permitidos
-			allowedOrigins := []string{
-				"http://165.22.139.71:8080",
-				"http://localhost:8080",
-				"http://127.0.0.1:8080",
-			}
+	// Rutas de la API
+	registerRoutes(http.DefaultServeMux)
 
-			// Verificar si el origen de la request está permitido
-			origin := r.Header.Get("Origin")
-			for _, allowedOrigin := range allowedOrigins {
-				if origin == allowedOrigin {
-					w.Header().Set("Access-Control-Allow-Origin", origin)
-					break
-				}
-			}
+	log.Printf("Servidor backend Go escuchando en el puerto :%s", apiPort)
+	if readOnly {
+		log.Println("Modo solo lectura activo: se rechazarán las escrituras")
+	}
 
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	srv := &http.Server{
+		Addr:    ":" + apiPort,
+		Handler: requestIDHandler(loggingHandler(serverTimingHandler(concurrencyLimitHandler(recoverHandler(readOnlyHandler(summaryCacheHandler(http.DefaultServeMux))))))),
+	}
+	if tlsEnabled() {
+		srv.TLSConfig = serverTLSConfig()
+		log.Printf("HTTPS activo: versión mínima de TLS %s", tls.VersionName(srv.TLSConfig.MinVersion))
+	}
+	// Job de instantáneas de resumen; se detiene junto con el servidor
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	snapshotJobDone := startSnapshotJob(jobCtx)
 
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-			h.ServeHTTP(w, r)
-		})
+	err = runServer(srv)
+	stopJobs()
+	<-snapshotJobDone
+	if err != nil {
+		// log.Fatalf no ejecutaría los defer: se cierra la base de datos antes de salir
+		db.Close()
+		log.Fatalf("Error del servidor: %v", err)
 	}
+	// Los defer cierran la base de datos ahora que no quedan peticiones en curso
+	log.Println("Cerrando la conexión a la base de datos")
+}
 
-	// Rutas de la API
-	http.Handle("/transactions", corsHandler(http.HandlerFunc(getTransactions)))
-	http.Handle("/transaction", corsHandler(http.HandlerFunc(createTransaction)))
-	http.Handle("/transaction/", corsHandler(http.HandlerFunc(handleTransactionByID))) // Para PUT y DELETE
+// Handler para /transactions (GET: obtener todas, o solo las indicadas con ?ids=1,2,3).
+// Con ?include_archived=true se incluyen también las transacciones archivadas.
+// Con ?q= filtra por descripción y con ?highlight=true añade las posiciones de cada coincidencia.
+// Con ?include_raw=true las importadas incluyen raw_source, su línea original del CSV.
+func getTransactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r, "GET")
+		return
+	}
 
-	log.Printf("Servidor backend Go escuchando en el puerto :%s", apiPort)
-	log.Fatal(http.ListenAndServe(":"+apiPort, nil))
+	query := r.URL.Query()
+	if query.Has("ids") {
+		getTransactionsByIDs(w, r)
+		return
+	}
+
+	filter, err := buildTransactionFilter(query)
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return
+	}
+	truncate, err := parseTruncate(query)
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return
+	}
+
+	withRaw := includeRawSource(r)
+	columns := selectTransactionColumns(withRaw)
+	listSQL := "SELECT " + columns + " FROM transactions" + filter.where()
+	if query.Get("include_archived") == "true" {
+		// Los marcadores $n se repiten en ambas mitades con los mismos argumentos
+		listSQL += " UNION ALL SELECT " + columns + " FROM transactions_archive" + filter.where()
+	}
+	rows, err := queryReadDB(r.Context(), "list_transactions", listSQL+" ORDER BY created_at DESC", filter.args...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	transactions := []Transaction{}
+	for rows.Next() {
+		var t Transaction
+		if err := scanSelectedTransaction(rows, &t, withRaw); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		truncateTimestamps(&t, truncate)
+		transactions = append(transactions, t)
+	}
+
+	if err := setDataWarning(w, r); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if query.Get("highlight") == "true" {
+		writeHighlightedTransactions(w, r, http.StatusOK, transactions, query.Get("q"))
+		return
+	}
+	writeTransactions(w, r, http.StatusOK, transactions)
 }
 
-// Handler para /transactions (GET: obtener todas)
-func getTransactions(w http.ResponseWriter, r *http.Request) {
+// Handler para /transactions (GET: listar, DELETE: borrar las que cumplan los filtros)
+func handleTransactions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		getTransactions(w, r)
+	case "DELETE":
+		deleteTransactions(w, r)
+	default:
+		methodNotAllowed(w, r, "GET", "DELETE")
+	}
+}
+
+// Handler para /transactions (DELETE: borrado masivo con los mismos filtros que la lista).
+// Exige ?confirm=true y al menos un filtro, para no vaciar la tabla por accidente. Como el
+// borrado individual, responde 409 si alguna tiene partes de una división, salvo con ?cascade=true.
+func deleteTransactions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("confirm") != "true" {
+		writeError(w, r, http.StatusBadRequest, msgConfirmRequired)
+		return
+	}
+
+	filter, err := buildTransactionFilter(query)
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return
+	}
+	if !filter.filtered() {
+		writeError(w, r, http.StatusBadRequest, msgUnfilteredDelete)
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// La misma regla que al borrar una sola: las que tienen partes solo se borran con ?cascade=true,
+	// y entonces se llevan sus partes; si no, estas quedarían huérfanas
+	var withChildren int
+	err = queryRowTx(ctx, tx, "delete_transactions_count_parents",
+		"SELECT COUNT(*) FROM transactions"+filter.where()+
+			" AND EXISTS (SELECT 1 FROM transactions c WHERE c.parent_id = transactions.id)", filter.args...).Scan(&withChildren)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	deleteSQL := "DELETE FROM transactions" + filter.where()
+	if withChildren > 0 {
+		if query.Get("cascade") != "true" {
+			writeError(w, r, http.StatusConflict, msgBulkHasChildren, withChildren)
+			return
+		}
+		deleteSQL = `
+			WITH RECURSIVE tree AS (
+				SELECT id FROM transactions` + filter.where() + `
+				UNION ALL
+				SELECT t.id FROM transactions t JOIN tree ON t.parent_id = tree.id
+			)
+			DELETE FROM transactions WHERE id IN (SELECT id FROM tree)`
+	}
+
+	res, err := execTx(ctx, tx, "delete_transactions", deleteSQL, filter.args...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]int64{"deleted": deleted})
+}
+
+// Handler para /transactions/recent (GET: las N transacciones más recientes, ?n= por defecto 10 y máximo 50)
+func getRecentTransactions(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
-		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		methodNotAllowed(w, r, "GET")
 		return
 	}
 
-	rows, err := db.Query("SELECT id, description, amount, type, created_at FROM transactions ORDER BY created_at DESC")
+	truncate, err := parseTruncate(r.URL.Query())
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return
+	}
+
+	n := defaultRecentCount
+	if raw := r.URL.Query().Get("n"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			writeError(w, r, http.StatusBadRequest, msgInvalidRecentCount)
+			return
+		}
+		n = min(parsed, maxRecentCount)
+	}
+
+	rows, err := queryReadDB(r.Context(), "recent_transactions",
+		"SELECT "+transactionColumns+" FROM transactions WHERE "+visibleTransactions+" ORDER BY created_at DESC LIMIT $1", n)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -133,66 +403,233 @@ func getTransactions(w http.ResponseWriter, r *http.Request) {
 	transactions := []Transaction{}
 	for rows.Next() {
 		var t Transaction
-		if err := rows.Scan(&t.ID, &t.Description, &t.Amount, &t.Type, &t.CreatedAt); err != nil {
+		if err := scanTransaction(rows, &t); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		truncateTimestamps(&t, truncate)
 		transactions = append(transactions, t)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(transactions)
+	writeTransactions(w, r, http.StatusOK, transactions)
+}
+
+// DateRange son la primera y la última fecha con transacciones (null si no hay ninguna)
+type DateRange struct {
+	Min *time.Time `json:"min"`
+	Max *time.Time `json:"max"`
+}
+
+// Handler para /transactions/date-range (GET: fechas mínima y máxima, para inicializar selectores)
+func getTransactionDateRange(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r, "GET")
+		return
+	}
+
+	var dr DateRange
+	err := queryRowReadDB(r.Context(), "transactions_date_range",
+		"SELECT MIN(created_at), MAX(created_at) FROM transactions WHERE "+visibleTransactions).Scan(&dr.Min, &dr.Max)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, dr)
+}
+
+// getTransactionsByIDs devuelve las transacciones de ?ids= en el mismo orden en que se pidieron.
+// Los ids inexistentes se omiten y los repetidos se devuelven una sola vez.
+func getTransactionsByIDs(w http.ResponseWriter, r *http.Request) {
+	// ?ids= no pasa por buildTransactionFilter: ?ids=1&ids=2 se rechaza aquí igual que allí
+	if err := checkSingleValued(r.URL.Query(), "ids"); err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return
+	}
+	ids, err := parseIDList(r.URL.Query().Get("ids"))
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return
+	}
+	truncate, err := parseTruncate(r.URL.Query())
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return
+	}
+
+	withRaw := includeRawSource(r)
+	rows, err := queryReadDB(r.Context(), "list_transactions_by_ids",
+		"SELECT "+selectTransactionColumns(withRaw)+" FROM transactions WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	found := make(map[int64]Transaction, len(ids))
+	for rows.Next() {
+		var t Transaction
+		if err := scanSelectedTransaction(rows, &t, withRaw); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		truncateTimestamps(&t, truncate)
+		found[int64(t.ID)] = t
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	transactions := []Transaction{}
+	for _, id := range ids {
+		if t, ok := found[id]; ok {
+			transactions = append(transactions, t)
+			delete(found, id)
+		}
+	}
+
+	writeTransactions(w, r, http.StatusOK, transactions)
+}
+
+// parseIDList convierte "1,2,3" en una lista de ids positivos
+func parseIDList(raw string) ([]int64, error) {
+	var ids []int64
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, newAPIError(msgInvalidIDList, part)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) > maxIDsPerRequest {
+		return nil, newAPIError(msgTooManyIDs, maxIDsPerRequest)
+	}
+	return ids, nil
 }
 
 // Handler para /transaction (POST: crear una nueva)
 func createTransaction(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
-		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		methodNotAllowed(w, r, "POST")
 		return
 	}
 
 	var t Transaction
-	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !decodeJSON(w, r, maxBodyBytes, &t) {
 		return
 	}
 
+	// Con ?infer_type=true el signo del importe decide el tipo: -19.99 es un gasto y 500 un ingreso
+	if r.URL.Query().Get("infer_type") == "true" {
+		if err := inferTypeFromSign(&t); err != nil {
+			http.Error(w, errorText(r, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Si se omite el tipo se usa el configurado por defecto (si lo hay)
+	if t.Type == "" {
+		t.Type = defaultTransactionType
+	}
+
 	// Validación básica
-	if t.Description == "" || t.Amount <= 0 || (t.Type != "income" && t.Type != "expense") {
-		http.Error(w, "Descripción, monto o tipo inválido", http.StatusBadRequest)
+	if t.Description == "" || !validAmount(t.Amount) || !isValidType(t.Type) {
+		writeError(w, r, http.StatusBadRequest, msgInvalidTransaction)
+		return
+	}
+	if amountTooLarge(t.Amount) {
+		writeError(w, r, http.StatusBadRequest, msgAmountTooLarge, maxAmount)
 		return
 	}
 
-	stmt, err := db.Prepare("INSERT INTO transactions(description, amount, type) VALUES($1, $2, $3) RETURNING id, created_at")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	t.Payee = normalizePayee(t.Payee)
+	t.Reference = normalizeReference(t.Reference)
+	// Con ?lenient=true las reglas de negocio no impiden crear la transacción: sus
+	// incumplimientos se devuelven como avisos. La validación básica sigue siendo obligatoria.
+	var warnings []string
+	if r.URL.Query().Get("lenient") == "true" {
+		warnings = checkRules(r, t)
+	} else if !enforceRules(w, r, t) {
+		return
+	}
+	t.Source = strings.TrimSpace(t.Source)
+	if t.Source == "" {
+		t.Source = defaultSource
+	}
+	if t.Status == "" {
+		t.Status = statusPosted
+	}
+	if !isValidStatus(t.Status) {
+		writeError(w, r, http.StatusBadRequest, msgInvalidStatus)
 		return
 	}
-	defer stmt.Close()
 
-	err = stmt.QueryRow(t.Description, t.Amount, t.Type).Scan(&t.ID, &t.CreatedAt)
+	if t.UUID != nil {
+		uuid, ok := normalizeUUID(*t.UUID)
+		if !ok {
+			writeError(w, r, http.StatusBadRequest, msgInvalidUUID)
+			return
+		}
+		t.UUID = &uuid
+	}
+
+	// La fecha la pone la aplicación (en APP_TIMEZONE, guardada en UTC) y no el reloj del
+	// servidor de base de datos; el cliente puede indicar la suya en created_at
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = appNow()
+	}
+
+	// Con uuid, repetir la creación no inserta otra fila: ON CONFLICT no devuelve nada y se
+	// responde 200 con la transacción que ya existía
+	err := queryRowDB(r.Context(), "create_transaction",
+		"INSERT INTO transactions(description, amount, type, source, cleared, payee, is_template, uuid, created_at, status, reference) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) ON CONFLICT (uuid) DO NOTHING RETURNING id, created_at, updated_at",
+		t.Description, storedAmount(t), t.Type, t.Source, t.Cleared, t.Payee, t.IsTemplate, t.UUID, t.CreatedAt.UTC(), t.Status, t.Reference).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+	if err == sql.ErrNoRows && t.UUID != nil {
+		existing, err := fetchTransactionByUUID(r.Context(), *t.UUID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, r, http.StatusOK, existing)
+		return
+	}
+	if isReferenceConflict(err) {
+		writeError(w, r, http.StatusConflict, msgDuplicateReference, *t.Reference)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(t)
+	if warnings != nil {
+		writeJSON(w, r, http.StatusCreated, createdWithWarnings{Transaction: t, Warnings: warnings})
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, t)
+}
+
+// createdWithWarnings es la respuesta de una creación en modo lenient: la transacción
+// con un campo warnings añadido (vacío si no incumplió ninguna regla)
+type createdWithWarnings struct {
+	Transaction
+	Warnings []string `json:"warnings"`
 }
 
 // Handler genérico para /transaction/{id} (PUT: actualizar, DELETE: borrar)
+// y sus subrecursos /transaction/{id}/{acción}
 func handleTransactionByID(w http.ResponseWriter, r *http.Request) {
 	// Extraer ID de la URL
-	pathParts := splitPath(r.URL.Path)
-	if len(pathParts) < 2 {
-		http.Error(w, "ID de transacción no proporcionado", http.StatusBadRequest)
+	id, rest, err := parseTransactionPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
 		return
 	}
-	idStr := pathParts[len(pathParts)-1] // Última parte de la URL
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		http.Error(w, "ID de transacción inválido", http.StatusBadRequest)
+
+	if len(rest) > 0 {
+		handleTransactionAction(w, r, id, rest[0])
 		return
 	}
 
@@ -204,13 +641,77 @@ func handleTransactionByID(w http.ResponseWriter, r *http.Request) {
 	case "GET": // Opcional: obtener una sola transacción por ID
 		getTransactionByID(w, r, id)
 	default:
-		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		methodNotAllowed(w, r, "GET", "PUT", "DELETE")
+	}
+}
+
+// transactionPathMethods devuelve los métodos que admite una ruta concreta bajo
+// /transaction/: GET, PUT y DELETE para /transaction/{id} y el de la acción para
+// /transaction/{id}/{acción}. false si el id no es válido o la acción no existe.
+func transactionPathMethods(path string) ([]string, bool) {
+	_, rest, err := parseTransactionPath(path)
+	if err != nil {
+		return nil, false
+	}
+	if len(rest) == 0 {
+		return []string{"GET", "PUT", "DELETE"}, true
+	}
+	action, ok := transactionActions[rest[0]]
+	if !ok {
+		return nil, false
+	}
+	return []string{action.method}, true
+}
+
+// transactionAction es un subrecurso de /transaction/{id}/ con el método que admite
+type transactionAction struct {
+	method  string
+	handler func(http.ResponseWriter, *http.Request, int)
+}
+
+// transactionActions son los subrecursos de /transaction/{id}/
+var transactionActions = map[string]transactionAction{
+	"receipt.pdf":  {"GET", getTransactionReceipt},
+	"split":        {"POST", splitTransaction},
+	"cleared":      {"PATCH", setTransactionCleared},
+	"use-template": {"POST", useTemplate},
+	"similar":      {"GET", getSimilarTransactions},
+	"status":       {"PATCH", setTransactionStatus},
+}
+
+// handleTransactionAction despacha los subrecursos de /transaction/{id}/
+func handleTransactionAction(w http.ResponseWriter, r *http.Request, id int, name string) {
+	action, ok := transactionActions[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != action.method {
+		methodNotAllowed(w, r, action.method)
+		return
 	}
+	action.handler(w, r, id)
 }
 
+// parseTransactionPath extrae el id de /transaction/{id}[/{acción}...] y los segmentos
+// que le siguen. Las barras finales o duplicadas se ignoran (/transaction/42/ es válido).
+func parseTransactionPath(path string) (int, []string, error) {
+	pathParts := splitPath(path)
+	if len(pathParts) < 2 {
+		return 0, nil, newAPIError(msgMissingID)
+	}
+	id, err := strconv.Atoi(pathParts[1]) // Parte siguiente a /transaction/
+	if err != nil {
+		return 0, nil, newAPIError(msgInvalidID)
+	}
+	return id, pathParts[2:], nil
+}
+
+// splitPath divide la ruta de la URL en segmentos no vacíos.
+// Las URLs siempre usan "/", independientemente del separador del sistema operativo.
 func splitPath(path string) []string {
 	var parts []string
-	for _, p := range strings.Split(path, string(os.PathSeparator)) {
+	for _, p := range strings.Split(path, "/") {
 		if p != "" {
 			parts = append(parts, p)
 		}
@@ -220,39 +721,67 @@ func splitPath(path string) []string {
 
 // Handler para /transaction/{id} (GET: obtener por ID)
 func getTransactionByID(w http.ResponseWriter, r *http.Request, id int) {
-	row := db.QueryRow("SELECT id, description, amount, type, created_at FROM transactions WHERE id = $1", id)
+	truncate, err := parseTruncate(r.URL.Query())
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return
+	}
 
 	var t Transaction
-	err := row.Scan(&t.ID, &t.Description, &t.Amount, &t.Type, &t.CreatedAt)
+	withRaw := includeRawSource(r)
+	err = scanSelectedTransaction(queryRowDB(r.Context(), "get_transaction",
+		"SELECT "+selectTransactionColumns(withRaw)+" FROM transactions WHERE id = $1", id), &t, withRaw)
 	if err == sql.ErrNoRows {
-		http.Error(w, "Transacción no encontrada", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, msgNotFound)
 		return
 	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	truncateTimestamps(&t, truncate)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(t)
+	writeTransaction(w, r, http.StatusOK, t)
+}
+
+// fetchTransaction lee una transacción por id; devuelve sql.ErrNoRows si no existe
+func fetchTransaction(ctx context.Context, id int) (Transaction, error) {
+	var t Transaction
+	err := scanTransaction(queryRowDB(ctx, "get_transaction",
+		"SELECT "+transactionColumns+" FROM transactions WHERE id = $1", id), &t)
+	return t, err
 }
 
 // Handler para /transaction/{id} (PUT: actualizar)
 func updateTransaction(w http.ResponseWriter, r *http.Request, id int) {
 	var t Transaction
-	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !decodeJSON(w, r, maxBodyBytes, &t) {
 		return
 	}
 
 	// Validación básica
-	if t.Description == "" || t.Amount <= 0 || (t.Type != "income" && t.Type != "expense") {
-		http.Error(w, "Descripción, monto o tipo inválido", http.StatusBadRequest)
+	if t.Description == "" || !validAmount(t.Amount) || !isValidType(t.Type) {
+		writeError(w, r, http.StatusBadRequest, msgInvalidTransaction)
+		return
+	}
+	if amountTooLarge(t.Amount) {
+		writeError(w, r, http.StatusBadRequest, msgAmountTooLarge, maxAmount)
 		return
 	}
 
-	res, err := db.Exec("UPDATE transactions SET description=$1, amount=$2, type=$3 WHERE id=$4",
-		t.Description, t.Amount, t.Type, id)
+	t.Payee = normalizePayee(t.Payee)
+	t.Reference = normalizeReference(t.Reference)
+	if !enforceRules(w, r, t) {
+		return
+	}
+
+	res, err := execDB(r.Context(), "update_transaction",
+		"UPDATE transactions SET description=$1, amount=$2, type=$3, payee=$4, reference=$5, updated_at=CURRENT_TIMESTAMP WHERE id=$6",
+		t.Description, storedAmount(t), t.Type, t.Payee, t.Reference, id)
+	if isReferenceConflict(err) {
+		writeError(w, r, http.StatusConflict, msgDuplicateReference, *t.Reference)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -264,17 +793,47 @@ func updateTransaction(w http.ResponseWriter, r *http.Request, id int) {
 		return
 	}
 	if rowsAffected == 0 {
-		http.Error(w, "Transacción no encontrada", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, msgNotFound)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, "Transacción %d actualizada correctamente", id)
+	fmt.Fprint(w, localize(r, msgUpdated, id))
 }
 
 // Handler para /transaction/{id} (DELETE: borrar)
 func deleteTransaction(w http.ResponseWriter, r *http.Request, id int) {
-	res, err := db.Exec("DELETE FROM transactions WHERE id=$1", id)
+	ctx := r.Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Una transacción dividida tiene partes que apuntan a ella: borrarla sola las dejaría
+	// huérfanas (parent_id pasaría a NULL), así que hace falta ?cascade=true
+	var children int
+	err = queryRowTx(ctx, tx, "delete_count_children",
+		"SELECT COUNT(*) FROM transactions WHERE parent_id = $1", id).Scan(&children)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	cascade := r.URL.Query().Get("cascade") == "true"
+	if children > 0 && !cascade {
+		writeError(w, r, http.StatusConflict, msgHasChildren, id, children)
+		return
+	}
+
+	// Con cascade se borran también las partes, y las partes de las partes si se dividieron
+	res, err := execTx(ctx, tx, "delete_transaction", `
+		WITH RECURSIVE tree AS (
+			SELECT id FROM transactions WHERE id = $1
+			UNION ALL
+			SELECT t.id FROM transactions t JOIN tree ON t.parent_id = tree.id
+		)
+		DELETE FROM transactions WHERE id IN (SELECT id FROM tree)`, id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -286,10 +845,46 @@ func deleteTransaction(w http.ResponseWriter, r *http.Request, id int) {
 		return
 	}
 	if rowsAffected == 0 {
-		http.Error(w, "Transacción no encontrada", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, msgNotFound)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, "Transacción %d eliminada correctamente", id)
+	if rowsAffected > 1 {
+		fmt.Fprint(w, localize(r, msgDeletedWithChildren, id, rowsAffected-1))
+		return
+	}
+	fmt.Fprint(w, localize(r, msgDeleted, id))
+}
+
+// Handler para /transaction/{id}/cleared (PATCH: marcar o desmarcar como conciliada)
+func setTransactionCleared(w http.ResponseWriter, r *http.Request, id int) {
+	var body struct {
+		Cleared *bool `json:"cleared"`
+	}
+	if !decodeJSON(w, r, maxBodyBytes, &body) {
+		return
+	}
+	if body.Cleared == nil {
+		writeError(w, r, http.StatusBadRequest, msgClearedRequired)
+		return
+	}
+
+	var t Transaction
+	err := scanTransaction(queryRowDB(r.Context(), "set_transaction_cleared",
+		"UPDATE transactions SET cleared=$1, updated_at=CURRENT_TIMESTAMP WHERE id=$2 RETURNING "+transactionColumns, *body.Cleared, id), &t)
+	if err == sql.ErrNoRows {
+		writeError(w, r, http.StatusNotFound, msgNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, t)
 }
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestParseTransactionPath(t *testing.T) {
+	cases := []struct {
+		path    string
+		id      int
+		rest    int
+		wantErr bool
+	}{
+		{"/transaction/42", 42, 0, false},
+		{"/transaction/42/", 42, 0, false},
+		{"/transaction//42//", 42, 0, false},
+		{"/transaction/42/split", 42, 1, false},
+		{"/transaction/abc", 0, 0, true},
+		{"/transaction/", 0, 0, true},
+	}
+	for _, c := range cases {
+		id, rest, err := parseTransactionPath(c.path)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: se esperaba un error", c.path)
+			}
+			continue
+		}
+		if err != nil || id != c.id || len(rest) != c.rest {
+			t.Errorf("%s = (%d, %v, %v), se esperaba id %d con %d segmentos", c.path, id, rest, err, c.id, c.rest)
+		}
+	}
+}
+
+func TestHandleTransactionByIDPaths(t *testing.T) {
+	for _, path := range []string{"/transaction/42", "/transaction/42/"} {
+		mock := newMockDB(t)
+		mock.ExpectQuery("SELECT .+ FROM transactions WHERE id = \\$1").
+			WithArgs(42).
+			WillReturnRows(addTransactionRow(transactionRows(), 42, "Café", "3.50", "expense"))
+
+		rec := serve(handleTransactionByID, "GET", path, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: estado %d: %s", path, rec.Code, rec.Body)
+		}
+		var got Transaction
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != 42 {
+			t.Errorf("GET %s devolvió el id %d", path, got.ID)
+		}
+	}
+
+	rec := serve(handleTransactionByID, "GET", "/transaction/abc", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("GET /transaction/abc: estado %d, se esperaba 400", rec.Code)
+	}
+}
+
+// expectInsert espera el INSERT de createTransaction con el tipo indicado
+func expectInsert(mock sqlmock.Sqlmock, typ string) {
+	mock.ExpectQuery("INSERT INTO transactions").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), typ, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(1, time.Now(), time.Now()))
+}
+
+func TestCreateTransactionDefaultType(t *testing.T) {
+	defer func() { defaultTransactionType = "" }()
+
+	cases := []struct {
+		name        string
+		defaultType string
+		body        string
+		status      int
+		stored      string // tipo con el que se inserta; "" si no llega a insertarse
+	}{
+		{"omitido con valor por defecto", "expense", `{"description":"Café","amount":3.5}`, http.StatusCreated, "expense"},
+		{"explícito con valor por defecto", "expense", `{"description":"Nómina","amount":1500,"type":"income"}`, http.StatusCreated, "income"},
+		{"explícito inválido", "expense", `{"description":"Café","amount":3.5,"type":"gasto"}`, http.StatusBadRequest, ""},
+		{"omitido sin valor por defecto", "", `{"description":"Café","amount":3.5}`, http.StatusBadRequest, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defaultTransactionType = c.defaultType
+			mock := newMockDB(t)
+			if c.stored != "" {
+				expectInsert(mock, c.stored)
+			}
+			rec := serve(createTransaction, "POST", "/transaction", c.body)
+			if rec.Code != c.status {
+				t.Fatalf("estado %d, se esperaba %d: %s", rec.Code, c.status, rec.Body)
+			}
+		})
+	}
+}
+
+// appTimeArg comprueba que created_at llega a la base de datos en UTC y con la hora indicada
+type appTimeArg struct {
+	want  time.Time
+	slack time.Duration
+}
+
+func (a appTimeArg) Match(v driver.Value) bool {
+	ts, ok := v.(time.Time)
+	if !ok || ts.Location() != time.UTC {
+		return false
+	}
+	d := ts.Sub(a.want)
+	return d >= -a.slack && d <= a.slack
+}
+
+func TestCreateTransactionCreatedAt(t *testing.T) {
+	defer func() { appLocation = time.UTC }()
+	appLocation = time.FixedZone("UTC-5", -5*3600)
+
+	explicit := time.Date(2024, 3, 1, 23, 30, 0, 0, appLocation)
+	cases := []struct {
+		name string
+		body string
+		want appTimeArg
+	}{
+		{"hora de la aplicación", `{"description":"Café","amount":3.5,"type":"expense"}`, appTimeArg{appNow(), 5 * time.Second}},
+		{"hora del cliente", `{"description":"Café","amount":3.5,"type":"expense","created_at":"2024-03-01T23:30:00-05:00"}`, appTimeArg{explicit, 0}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := newMockDB(t)
+			mock.ExpectQuery("INSERT INTO transactions").
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "expense", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+					sqlmock.AnyArg(), sqlmock.AnyArg(), c.want, sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(1, time.Now(), time.Now()))
+			rec := serve(createTransaction, "POST", "/transaction", c.body)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestDeleteTransactionChildren(t *testing.T) {
+	cases := []struct {
+		name     string
+		target   string
+		children int
+		deleted  int64 // filas borradas; -1 si no se llega a borrar
+		status   int
+	}{
+		{"sin partes", "/transaction/7", 0, 1, http.StatusOK},
+		{"con partes sin cascade", "/transaction/7", 2, -1, http.StatusConflict},
+		{"con partes y cascade", "/transaction/7?cascade=true", 2, 3, http.StatusOK},
+		{"inexistente", "/transaction/7", 0, 0, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := newMockDB(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM transactions WHERE parent_id = \\$1").
+				WithArgs(7).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(c.children))
+			if c.deleted >= 0 {
+				mock.ExpectExec("DELETE FROM transactions WHERE id IN").
+					WithArgs(7).
+					WillReturnResult(sqlmock.NewResult(0, c.deleted))
+			}
+			if c.deleted > 0 {
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			rec := serve(handleTransactionByID, "DELETE", c.target, "")
+			if rec.Code != c.status {
+				t.Fatalf("estado %d, se esperaba %d: %s", rec.Code, c.status, rec.Body)
+			}
+		})
+	}
+}
+
+func TestInferTypeFromSignZero(t *testing.T) {
+	defer func() { allowZeroAmount = false }()
+
+	cases := []struct {
+		allowZero bool
+		typ       string
+		wantErr   bool
+	}{
+		{false, "", true},
+		{false, "expense", true},
+		{true, "", true}, // sin tipo, un 0 no tiene signo del que deducirlo
+		{true, "expense", false},
+	}
+	for _, c := range cases {
+		allowZeroAmount = c.allowZero
+		tx := Transaction{Amount: mustAmount(t, "0"), Type: c.typ}
+		err := inferTypeFromSign(&tx)
+		if (err != nil) != c.wantErr {
+			t.Errorf("ALLOW_ZERO_AMOUNT=%t tipo %q: error %v", c.allowZero, c.typ, err)
+		}
+		if err != nil && apiErrorKey(err) != msgZeroAmount {
+			t.Errorf("ALLOW_ZERO_AMOUNT=%t tipo %q: error %v, se esperaba %s", c.allowZero, c.typ, err, msgZeroAmount)
+		}
+	}
+}
+
+func TestCreateTransactionZeroAmount(t *testing.T) {
+	defer func() { allowZeroAmount = false }()
+
+	for _, allowZero := range []bool{false, true} {
+		allowZeroAmount = allowZero
+		mock := newMockDB(t)
+		want := http.StatusBadRequest
+		if allowZero {
+			want = http.StatusCreated
+			expectInsert(mock, "expense")
+		}
+		rec := serve(createTransaction, "POST", "/transaction", `{"description":"Ajuste","amount":0,"type":"expense"}`)
+		if rec.Code != want {
+			t.Errorf("ALLOW_ZERO_AMOUNT=%t: estado %d, se esperaba %d: %s", allowZero, rec.Code, want, rec.Body)
+		}
+	}
+}
+
+func TestCreateTransactionMaxAmount(t *testing.T) {
+	mock := newMockDB(t)
+	expectInsert(mock, "income")
+	rec := serve(createTransaction, "POST", "/transaction", `{"description":"Tope","amount":"99999999.99","type":"income"}`)
+	if rec.Code != http.StatusCreated {
+		t.Errorf("99999999.99: estado %d, se esperaba 201: %s", rec.Code, rec.Body)
+	}
+
+	rec = serve(createTransaction, "POST", "/transaction", `{"description":"Tope","amount":"100000000.00","type":"income"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("100000000.00: estado %d, se esperaba 400", rec.Code)
+	}
+}
+
+func TestDeleteTransactionsWithChildren(t *testing.T) {
+	cases := []struct {
+		name     string
+		target   string
+		parents  int
+		deleteRe string // "" si no se llega a borrar
+		status   int
+	}{
+		{"sin partes", "/transactions?confirm=true&source=banco", 0, "^DELETE FROM transactions WHERE", http.StatusOK},
+		{"con partes sin cascade", "/transactions?confirm=true&source=banco", 1, "", http.StatusConflict},
+		{"con partes y cascade", "/transactions?confirm=true&source=banco&cascade=true", 1, "WITH RECURSIVE tree .+ DELETE FROM transactions WHERE id IN", http.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := newMockDB(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM transactions WHERE .+ AND EXISTS \\(SELECT 1 FROM transactions c WHERE c.parent_id = transactions.id\\)").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(c.parents))
+			if c.deleteRe != "" {
+				mock.ExpectExec(c.deleteRe).WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			rec := serve(handleTransactions, "DELETE", c.target, "")
+			if rec.Code != c.status {
+				t.Fatalf("estado %d, se esperaba %d: %s", rec.Code, c.status, rec.Body)
+			}
+		})
+	}
+}
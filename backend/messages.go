@@ -0,0 +1,353 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// msgKey identifica un mensaje traducible de la API
+type msgKey string
+
+const (
+	msgMethodNotAllowed         msgKey = "method_not_allowed"
+	msgInvalidTransaction       msgKey = "invalid_transaction"
+	msgMissingID                msgKey = "missing_id"
+	msgInvalidID                msgKey = "invalid_id"
+	msgNotFound                 msgKey = "not_found"
+	msgUpdated                  msgKey = "updated"
+	msgDeleted                  msgKey = "deleted"
+	msgInvalidRecentCount       msgKey = "invalid_recent_count"
+	msgInvalidIDList            msgKey = "invalid_id_list"
+	msgTooManyIDs               msgKey = "too_many_ids"
+	msgInvalidClearedFilter     msgKey = "invalid_cleared_filter"
+	msgClearedRequired          msgKey = "cleared_required"
+	msgReadOnly                 msgKey = "read_only"
+	msgInvalidDate              msgKey = "invalid_date"
+	msgFromAfterTo              msgKey = "from_after_to"
+	msgRangeTooLarge            msgKey = "range_too_large"
+	msgInvalidFill              msgKey = "invalid_fill"
+	msgInvalidMonths            msgKey = "invalid_months"
+	msgInvalidAmount            msgKey = "invalid_amount"
+	msgAmountNotDecimal         msgKey = "amount_not_decimal"
+	msgUnauthorized             msgKey = "unauthorized"
+	msgInternalError            msgKey = "internal_error"
+	msgBodyTooLarge             msgKey = "body_too_large"
+	msgInvalidTypeFilter        msgKey = "invalid_type_filter"
+	msgConfirmRequired          msgKey = "confirm_required"
+	msgUnfilteredDelete         msgKey = "unfiltered_delete"
+	msgInvalidTruncate          msgKey = "invalid_truncate"
+	msgSplitTooFewParts         msgKey = "split_too_few_parts"
+	msgSplitInvalidPart         msgKey = "split_invalid_part"
+	msgSplitSumMismatch         msgKey = "split_sum_mismatch"
+	msgAlreadySplit             msgKey = "already_split"
+	msgInvalidWeekday           msgKey = "invalid_weekday"
+	msgArchiveMismatch          msgKey = "archive_mismatch"
+	msgZeroAmount               msgKey = "zero_amount"
+	msgTypeSignMismatch         msgKey = "type_sign_mismatch"
+	msgDataWarning              msgKey = "data_warning"
+	msgInvalidPivotBy           msgKey = "invalid_pivot_by"
+	msgInvalidPivotPeriod       msgKey = "invalid_pivot_period"
+	msgTooManyPeriods           msgKey = "too_many_periods"
+	msgNotTemplate              msgKey = "not_template"
+	msgTemplateSplit            msgKey = "template_split"
+	msgInvalidMissingField      msgKey = "invalid_missing_field"
+	msgInvalidUUID              msgKey = "invalid_uuid"
+	msgRuleViolations           msgKey = "rule_violations"
+	msgRulePayeeRequired        msgKey = "rule_payee_required"
+	msgRuleDisallowedPayee      msgKey = "rule_disallowed_payee"
+	msgAnomalyExpenseKeyword    msgKey = "anomaly_expense_keyword"
+	msgAnomalyOutlier           msgKey = "anomaly_outlier"
+	msgInvalidIDParam           msgKey = "invalid_id_param"
+	msgIDRangeInverted          msgKey = "id_range_inverted"
+	msgInvalidPercentile        msgKey = "invalid_percentile"
+	msgTooManyPercentiles       msgKey = "too_many_percentiles"
+	msgHasChildren              msgKey = "has_children"
+	msgDeletedWithChildren      msgKey = "deleted_with_children"
+	msgTooManyRequests          msgKey = "too_many_requests"
+	msgSimilarUnavailable       msgKey = "similar_unavailable"
+	msgInvalidSimilarLimit      msgKey = "invalid_similar_limit"
+	msgInvalidSummarySource     msgKey = "invalid_summary_source"
+	msgImportNoHeader           msgKey = "import_no_header"
+	msgImportEmptyDescription   msgKey = "import_empty_description"
+	msgImportInvalidType        msgKey = "import_invalid_type"
+	msgImportInvalidAmount      msgKey = "import_invalid_amount"
+	msgImportInvalidDate        msgKey = "import_invalid_date"
+	msgImportUnknownColumn      msgKey = "import_unknown_column"
+	msgImportDebitAndCredit     msgKey = "import_debit_and_credit"
+	msgImportInvalidDateFormat  msgKey = "import_invalid_date_format"
+	msgInvalidBulkSize          msgKey = "invalid_bulk_size"
+	msgBulkMissingUUID          msgKey = "bulk_missing_uuid"
+	msgBulkDuplicateUUID        msgKey = "bulk_duplicate_uuid"
+	msgBulkInvalidItems         msgKey = "bulk_invalid_items"
+	msgNotReady                 msgKey = "not_ready"
+	msgInvalidStatus            msgKey = "invalid_status"
+	msgInvalidIncludePending    msgKey = "invalid_include_pending"
+	msgInvalidPayeeSummaryLimit msgKey = "invalid_payee_summary_limit"
+	msgDuplicateParam           msgKey = "duplicate_param"
+	msgAmountTooLarge           msgKey = "amount_too_large"
+	msgInvalidConvertTo         msgKey = "invalid_convert_to"
+	msgMissingFXRate            msgKey = "missing_fx_rate"
+	msgConvertToSnapshot        msgKey = "convert_to_snapshot"
+	msgInvalidLastDays          msgKey = "invalid_last_days"
+	msgLastDaysWithRange        msgKey = "last_days_with_range"
+	msgRuleDescriptionTooLong   msgKey = "rule_description_too_long"
+	msgDuplicateReference       msgKey = "duplicate_reference"
+	msgInvalidSince             msgKey = "invalid_since"
+	msgImportMissingFields      msgKey = "import_missing_fields"
+	msgImportInvalidRows        msgKey = "import_invalid_rows"
+	msgBulkHasChildren          msgKey = "bulk_has_children"
+)
+
+// Idioma por defecto, el mismo en que estaban escritos los mensajes originales
+const defaultLang = "es"
+
+var messages = map[string]map[msgKey]string{
+	"es": {
+		msgMethodNotAllowed:         "Método no permitido",
+		msgInvalidTransaction:       "Descripción, monto o tipo inválido",
+		msgMissingID:                "ID de transacción no proporcionado",
+		msgInvalidID:                "ID de transacción inválido",
+		msgNotFound:                 "Transacción no encontrada",
+		msgUpdated:                  "Transacción %d actualizada correctamente",
+		msgDeleted:                  "Transacción %d eliminada correctamente",
+		msgInvalidRecentCount:       "El parámetro n debe ser un entero positivo",
+		msgInvalidIDList:            "Lista de ids inválida: %q",
+		msgTooManyIDs:               "Se admiten como máximo %d ids por petición",
+		msgInvalidClearedFilter:     "El parámetro cleared debe ser true o false",
+		msgClearedRequired:          "El campo 'cleared' es obligatorio",
+		msgReadOnly:                 "El servicio está en modo solo lectura por mantenimiento; inténtalo más tarde",
+		msgInvalidDate:              "Fecha '%s' inválida, usa el formato YYYY-MM-DD",
+		msgFromAfterTo:              "'from' no puede ser posterior a 'to'",
+		msgRangeTooLarge:            "El rango no puede superar %d días",
+		msgInvalidFill:              "El parámetro fill debe ser none, forward o zero",
+		msgInvalidMonths:            "El parámetro months debe ser un entero entre 1 y %d",
+		msgInvalidAmount:            "Importe inválido: %s",
+		msgAmountNotDecimal:         "El importe debe ser un número decimal sin notación científica: %s",
+		msgUnauthorized:             "No autorizado",
+		msgInternalError:            "Error interno del servidor",
+		msgBodyTooLarge:             "El cuerpo de la petición supera el límite de %d bytes",
+		msgInvalidTypeFilter:        "El parámetro type debe ser income o expense",
+		msgConfirmRequired:          "Añade ?confirm=true para confirmar el borrado",
+		msgUnfilteredDelete:         "El borrado masivo necesita al menos un filtro (type, from, to...)",
+		msgInvalidTruncate:          "El parámetro truncate debe ser minute, hour o day",
+		msgSplitTooFewParts:         "Una división necesita al menos %d partes",
+		msgSplitInvalidPart:         "La parte %d tiene un monto inválido",
+		msgSplitSumMismatch:         "Las partes suman %s pero la transacción original es de %s",
+		msgAlreadySplit:             "La transacción ya está dividida",
+		msgInvalidWeekday:           "Día de la semana inválido: %q (usa mon, tue, wed, thu, fri, sat o sun)",
+		msgArchiveMismatch:          "Se copiaron %d filas pero se iban a borrar %d; no se ha archivado nada",
+		msgZeroAmount:               "El monto no puede ser 0",
+		msgTypeSignMismatch:         "El tipo %q no coincide con el signo del monto",
+		msgInvalidPivotBy:           "El parámetro by debe ser type o payee",
+		msgInvalidPivotPeriod:       "El parámetro period debe ser day, week, month o year",
+		msgTooManyPeriods:           "El rango abarca más de %d periodos; acota from/to o usa un periodo mayor",
+		msgDataWarning:              "La tabla tiene unas %d transacciones (umbral: %d); considera archivar las antiguas con POST /transactions/archive",
+		msgNotTemplate:              "La transacción %d no es una plantilla",
+		msgTemplateSplit:            "Una plantilla no se puede dividir",
+		msgInvalidMissingField:      "Campo %q no válido en missing (usa description o payee)",
+		msgInvalidUUID:              "El uuid no tiene un formato válido",
+		msgRuleViolations:           "La transacción incumple reglas de negocio",
+		msgRulePayeeRequired:        "Los gastos de más de %s requieren beneficiario",
+		msgRuleDisallowedPayee:      "El beneficiario %q no está permitido",
+		msgAnomalyExpenseKeyword:    "Es un ingreso pero la descripción parece de un gasto",
+		msgAnomalyOutlier:           "Importe muy alejado de lo habitual para este beneficiario (media %s)",
+		msgInvalidIDParam:           "El parámetro %s debe ser un entero positivo",
+		msgIDRangeInverted:          "id_gte no puede ser mayor que id_lte",
+		msgInvalidPercentile:        "Percentil %q no válido: debe ser un número mayor que 0 y como mucho 100",
+		msgTooManyPercentiles:       "Se pueden pedir como mucho %d percentiles",
+		msgHasChildren:              "La transacción %d tiene %d partes de una división; usa ?cascade=true para borrarlas también",
+		msgDeletedWithChildren:      "Transacción %d eliminada junto con %d partes",
+		msgTooManyRequests:          "El servidor está atendiendo demasiadas peticiones; inténtalo de nuevo en unos segundos",
+		msgSimilarUnavailable:       "La búsqueda de transacciones parecidas no está disponible (falta la extensión pg_trgm)",
+		msgInvalidSimilarLimit:      "El parámetro limit debe estar entre 1 y %d",
+		msgInvalidSummarySource:     "El parámetro source debe ser live o snapshot",
+		msgImportNoHeader:           "El CSV está vacío o no tiene una cabecera legible",
+		msgImportEmptyDescription:   "La descripción está vacía",
+		msgImportInvalidType:        "Tipo %q no válido (usa income o expense)",
+		msgImportInvalidAmount:      "Importe %q no válido",
+		msgImportInvalidDate:        "Fecha %q no reconocida",
+		msgImportUnknownColumn:      "No hay ninguna columna %q para el campo %s",
+		msgImportDebitAndCredit:     "La fila tiene valor en debit y en credit a la vez",
+		msgImportInvalidDateFormat:  "Formato de fecha %q no válido: usa iso, eu, us, dot, rfc3339 o un layout de Go como 02/01/2006",
+		msgInvalidBulkSize:          "El lote debe tener entre 1 y %d transacciones",
+		msgBulkMissingUUID:          "Falta el uuid, necesario para el upsert",
+		msgBulkDuplicateUUID:        "El uuid %s aparece más de una vez en el lote",
+		msgBulkInvalidItems:         "%d transacciones del lote no son válidas; no se ha guardado nada",
+		msgNotReady:                 "La base de datos no responde: %v",
+		msgInvalidStatus:            "El estado debe ser pending o posted",
+		msgInvalidIncludePending:    "El parámetro include_pending debe ser true o false",
+		msgInvalidPayeeSummaryLimit: "El parámetro limit debe ser un entero entre 1 y %d",
+		msgDuplicateParam:           "El parámetro %s solo admite un valor y aparece más de una vez",
+		msgAmountTooLarge:           "El monto no puede superar %s",
+		msgInvalidConvertTo:         "El parámetro convert_to debe ser un código de moneda ISO como EUR",
+		msgMissingFXRate:            "No hay tipo de cambio de %s a %s; configúralo en FX_RATES",
+		msgConvertToSnapshot:        "convert_to no se admite con source=snapshot",
+		msgInvalidLastDays:          "El parámetro last_days debe ser un entero entre 1 y %d",
+		msgLastDaysWithRange:        "last_days no se puede combinar con from ni to",
+		msgRuleDescriptionTooLong:   "La descripción no puede superar %d caracteres",
+		msgDuplicateReference:       "Ya existe una transacción con la referencia %s",
+		msgInvalidSince:             "El parámetro since es obligatorio y debe ser una fecha RFC 3339 (2024-06-01T10:00:00Z)",
+		msgImportMissingFields:      "Faltan columnas para: %s",
+		msgImportInvalidRows:        "%d filas del CSV no son válidas; no se ha importado ninguna",
+		msgBulkHasChildren:          "%d de las transacciones a borrar tienen partes de una división; usa ?cascade=true para borrarlas también",
+	},
+	"en": {
+		msgMethodNotAllowed:         "Method not allowed",
+		msgInvalidTransaction:       "Invalid description, amount or type",
+		msgMissingID:                "Transaction ID not provided",
+		msgInvalidID:                "Invalid transaction ID",
+		msgNotFound:                 "Transaction not found",
+		msgUpdated:                  "Transaction %d updated successfully",
+		msgDeleted:                  "Transaction %d deleted successfully",
+		msgInvalidRecentCount:       "The n parameter must be a positive integer",
+		msgInvalidIDList:            "Invalid id list: %q",
+		msgTooManyIDs:               "At most %d ids are allowed per request",
+		msgInvalidClearedFilter:     "The cleared parameter must be true or false",
+		msgClearedRequired:          "The 'cleared' field is required",
+		msgReadOnly:                 "The service is in read-only mode for maintenance; try again later",
+		msgInvalidDate:              "Invalid '%s' date, use the YYYY-MM-DD format",
+		msgFromAfterTo:              "'from' cannot be after 'to'",
+		msgRangeTooLarge:            "The range cannot exceed %d days",
+		msgInvalidFill:              "The fill parameter must be none, forward or zero",
+		msgInvalidMonths:            "The months parameter must be an integer between 1 and %d",
+		msgInvalidAmount:            "Invalid amount: %s",
+		msgAmountNotDecimal:         "The amount must be a plain decimal number, without scientific notation: %s",
+		msgUnauthorized:             "Unauthorized",
+		msgInternalError:            "Internal server error",
+		msgBodyTooLarge:             "The request body exceeds the %d byte limit",
+		msgInvalidTypeFilter:        "The type parameter must be income or expense",
+		msgConfirmRequired:          "Add ?confirm=true to confirm the deletion",
+		msgUnfilteredDelete:         "Bulk deletion requires at least one filter (type, from, to...)",
+		msgInvalidTruncate:          "The truncate parameter must be minute, hour or day",
+		msgSplitTooFewParts:         "A split needs at least %d parts",
+		msgSplitInvalidPart:         "Part %d has an invalid amount",
+		msgSplitSumMismatch:         "The parts add up to %s but the original transaction is %s",
+		msgAlreadySplit:             "The transaction is already split",
+		msgInvalidWeekday:           "Invalid weekday: %q (use mon, tue, wed, thu, fri, sat or sun)",
+		msgArchiveMismatch:          "Copied %d rows but %d were going to be deleted; nothing was archived",
+		msgZeroAmount:               "The amount cannot be 0",
+		msgTypeSignMismatch:         "The type %q does not match the sign of the amount",
+		msgInvalidPivotBy:           "The by parameter must be type or payee",
+		msgInvalidPivotPeriod:       "The period parameter must be day, week, month or year",
+		msgTooManyPeriods:           "The range spans more than %d periods; narrow from/to or use a larger period",
+		msgDataWarning:              "The table holds about %d transactions (threshold: %d); consider archiving old ones with POST /transactions/archive",
+		msgNotTemplate:              "Transaction %d is not a template",
+		msgTemplateSplit:            "A template cannot be split",
+		msgInvalidMissingField:      "Invalid field %q in missing (use description or payee)",
+		msgInvalidUUID:              "The uuid is not in a valid format",
+		msgRuleViolations:           "The transaction breaks business rules",
+		msgRulePayeeRequired:        "Expenses over %s require a payee",
+		msgRuleDisallowedPayee:      "The payee %q is not allowed",
+		msgAnomalyExpenseKeyword:    "Recorded as income but the description looks like an expense",
+		msgAnomalyOutlier:           "Amount far from usual for this payee (average %s)",
+		msgInvalidIDParam:           "The %s parameter must be a positive integer",
+		msgIDRangeInverted:          "id_gte cannot be greater than id_lte",
+		msgInvalidPercentile:        "Invalid percentile %q: it must be a number greater than 0 and at most 100",
+		msgTooManyPercentiles:       "At most %d percentiles can be requested",
+		msgHasChildren:              "Transaction %d has %d split parts; use ?cascade=true to delete them too",
+		msgDeletedWithChildren:      "Transaction %d deleted along with %d parts",
+		msgTooManyRequests:          "The server is handling too many requests; try again in a few seconds",
+		msgSimilarUnavailable:       "Similar-transaction search is unavailable (the pg_trgm extension is missing)",
+		msgInvalidSimilarLimit:      "The limit parameter must be between 1 and %d",
+		msgInvalidSummarySource:     "The source parameter must be live or snapshot",
+		msgImportNoHeader:           "The CSV is empty or has no readable header",
+		msgImportEmptyDescription:   "The description is empty",
+		msgImportInvalidType:        "Invalid type %q (use income or expense)",
+		msgImportInvalidAmount:      "Invalid amount %q",
+		msgImportInvalidDate:        "Unrecognized date %q",
+		msgImportUnknownColumn:      "There is no column %q for the field %s",
+		msgImportDebitAndCredit:     "The row has values in both debit and credit",
+		msgImportInvalidDateFormat:  "Invalid date format %q: use iso, eu, us, dot, rfc3339 or a Go layout such as 02/01/2006",
+		msgInvalidBulkSize:          "The batch must contain between 1 and %d transactions",
+		msgBulkMissingUUID:          "The uuid is missing; it is required for upserts",
+		msgBulkDuplicateUUID:        "The uuid %s appears more than once in the batch",
+		msgBulkInvalidItems:         "%d transactions in the batch are invalid; nothing was saved",
+		msgNotReady:                 "The database is not responding: %v",
+		msgInvalidStatus:            "The status must be pending or posted",
+		msgInvalidIncludePending:    "The include_pending parameter must be true or false",
+		msgInvalidPayeeSummaryLimit: "The limit parameter must be an integer between 1 and %d",
+		msgDuplicateParam:           "The %s parameter takes a single value but appears more than once",
+		msgAmountTooLarge:           "The amount cannot exceed %s",
+		msgInvalidConvertTo:         "The convert_to parameter must be an ISO currency code such as EUR",
+		msgMissingFXRate:            "There is no exchange rate from %s to %s; configure it in FX_RATES",
+		msgConvertToSnapshot:        "convert_to is not supported with source=snapshot",
+		msgInvalidLastDays:          "The last_days parameter must be an integer between 1 and %d",
+		msgLastDaysWithRange:        "last_days cannot be combined with from or to",
+		msgRuleDescriptionTooLong:   "The description cannot exceed %d characters",
+		msgDuplicateReference:       "A transaction with reference %s already exists",
+		msgInvalidSince:             "The since parameter is required and must be an RFC 3339 timestamp (2024-06-01T10:00:00Z)",
+		msgImportMissingFields:      "Missing columns for: %s",
+		msgImportInvalidRows:        "%d CSV rows are invalid; nothing was imported",
+		msgBulkHasChildren:          "%d of the transactions to delete have split parts; use ?cascade=true to delete them too",
+	},
+}
+
+// translate devuelve el mensaje en el idioma pedido, o en español si falta la traducción
+func translate(lang string, key msgKey, args ...any) string {
+	text, ok := messages[lang][key]
+	if !ok {
+		text = messages[defaultLang][key]
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+// requestLang elige el idioma soportado con mayor peso en Accept-Language
+func requestLang(r *http.Request) string {
+	best, bestQ := defaultLang, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if _, ok := messages[lang]; !ok {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// localize traduce un mensaje al idioma de la petición
+func localize(r *http.Request, key msgKey, args ...any) string {
+	return translate(requestLang(r), key, args...)
+}
+
+// writeError responde con un mensaje de error traducido
+func writeError(w http.ResponseWriter, r *http.Request, status int, key msgKey, args ...any) {
+	http.Error(w, localize(r, key, args...), status)
+}
+
+// apiError es un error de validación que se traduce al idioma de la petición al responder
+type apiError struct {
+	key  msgKey
+	args []any
+}
+
+func newAPIError(key msgKey, args ...any) error {
+	return &apiError{key: key, args: args}
+}
+
+func (e *apiError) Error() string {
+	return translate(defaultLang, e.key, e.args...)
+}
+
+// errorText devuelve el texto de err traducido si es un apiError
+func errorText(r *http.Request, err error) string {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return localize(r, apiErr.key, apiErr.args...)
+	}
+	return err.Error()
+}
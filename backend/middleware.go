@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// Modo solo lectura (READ_ONLY=true): se rechazan las escrituras durante un mantenimiento
+var readOnly = false
+
+// Segundos sugeridos en Retry-After mientras el modo solo lectura está activo
+const readOnlyRetryAfter = "120"
+
+// readOnlyHandler rechaza POST/PUT/PATCH/DELETE con 503 si el modo solo lectura está activo.
+// Las lecturas (GET) y OPTIONS siguen funcionando.
+func readOnlyHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if readOnly && isWriteMethod(r.Method) {
+			w.Header().Set("Retry-After", readOnlyRetryAfter)
+			writeError(w, r, http.StatusServiceUnavailable, msgReadOnly)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func isWriteMethod(method string) bool {
+	switch method {
+	case "POST", "PUT", "PATCH", "DELETE":
+		return true
+	}
+	return false
+}
+
+// recoverHandler captura los panics de los handlers, los registra con el id de la petición
+// y responde un 500 en JSON en lugar de cortar la conexión
+func recoverHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			log.Printf("panic en %s %s (request_id=%s): %v\n%s",
+				r.Method, r.URL.Path, requestIDFromContext(r.Context()), rec, debug.Stack())
+			writeJSON(w, r, http.StatusInternalServerError, map[string]string{
+				"error":      localize(r, msgInternalError),
+				"request_id": requestIDFromContext(r.Context()),
+			})
+		}()
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// normalizePayee colapsa los espacios del beneficiario; si queda vacío devuelve nil (NULL)
+func normalizePayee(p *string) *string {
+	if p == nil {
+		return nil
+	}
+	normalized := strings.Join(strings.Fields(*p), " ")
+	if normalized == "" {
+		return nil
+	}
+	return &normalized
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// Handler para /payees (GET: lista de beneficiarios distintos, ordenada alfabéticamente)
+func getPayees(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r, "GET")
+		return
+	}
+
+	rows, err := queryReadDB(r.Context(), "list_payees",
+		"SELECT DISTINCT payee FROM transactions WHERE payee IS NOT NULL AND "+visibleTransactions+" ORDER BY payee")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	payees := []string{}
+	for rows.Next() {
+		var payee string
+		if err := rows.Scan(&payee); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		payees = append(payees, payee)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, payees)
+}
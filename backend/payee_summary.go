@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Número de beneficiarios por defecto y máximo de /summary/by-payee
+const (
+	defaultPayeeSummaryLimit = 10
+	maxPayeeSummaryLimit     = 100
+)
+
+// PayeeTotal es el total y el número de transacciones de un beneficiario
+type PayeeTotal struct {
+	Payee string `json:"payee"`
+	Total Amount `json:"total"`
+	Count int    `json:"count"`
+}
+
+// Handler para /summary/by-payee (GET: total y número de transacciones por beneficiario,
+// de mayor a menor total, con ?limit= hasta 100). Acepta los mismos filtros que la lista
+// (from, to, type...). Las transacciones sin beneficiario se agrupan bajo noPayeeLabel y el
+// total suma el importe sin signo, así que para ver a quién se paga más conviene ?type=expense.
+func getSummaryByPayee(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r, "GET")
+		return
+	}
+
+	query := r.URL.Query()
+	limit := defaultPayeeSummaryLimit
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxPayeeSummaryLimit {
+			writeError(w, r, http.StatusBadRequest, msgInvalidPayeeSummaryLimit, maxPayeeSummaryLimit)
+			return
+		}
+		limit = n
+	}
+
+	filter, err := buildTransactionFilter(query)
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := queryReadDB(r.Context(), "summary_by_payee",
+		"SELECT "+pivotDimensions["payee"]+", SUM("+magnitudeSQL()+"), COUNT(*) FROM transactions"+filter.where()+
+			" GROUP BY 1 ORDER BY 2 DESC, 1 LIMIT "+filter.arg(limit),
+		filter.args...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	totals := []PayeeTotal{}
+	for rows.Next() {
+		var p PayeeTotal
+		if err := rows.Scan(&p.Payee, &p.Total, &p.Count); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		totals = append(totals, p)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, totals)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+// Percentiles por defecto de /summary/percentiles y máximo que se pueden pedir a la vez
+var defaultPercentiles = []float64{50, 90, 99}
+
+const maxPercentiles = 10
+
+// PercentileValue es el importe por debajo del cual queda el P% de las transacciones.
+// Amount es null si ninguna transacción cumple el filtro.
+type PercentileValue struct {
+	P      float64 `json:"p"`
+	Amount *Amount `json:"amount"`
+}
+
+// Percentiles son los percentiles pedidos y el número de transacciones sobre las que se calculan
+type Percentiles struct {
+	Count       int               `json:"count"`
+	Percentiles []PercentileValue `json:"percentiles"`
+}
+
+// Handler para /summary/percentiles (GET: percentiles ?p=50,90,99 de los importes).
+// Acepta los filtros de la lista (type, from, to...); conviene filtrar por tipo para no
+// mezclar ingresos y gastos. Se calculan con percentile_cont (interpolación lineal).
+func getSummaryPercentiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r, "GET")
+		return
+	}
+
+	query := r.URL.Query()
+	ps, err := parsePercentiles(query.Get("p"))
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return
+	}
+	filter, err := buildTransactionFilter(query)
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return
+	}
+
+	fractions := make([]float64, len(ps))
+	for i, p := range ps {
+		fractions[i] = p / 100
+	}
+	var count int
+	var values pq.Float64Array
+	err = queryRowReadDB(r.Context(), "summary_percentiles",
+		"SELECT COUNT(*), percentile_cont("+filter.arg(pq.Array(fractions))+"::float8[]) WITHIN GROUP (ORDER BY "+magnitudeSQL()+
+			") FROM transactions"+filter.where(), filter.args...).Scan(&count, &values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result := Percentiles{Count: count, Percentiles: make([]PercentileValue, len(ps))}
+	for i, p := range ps {
+		result.Percentiles[i].P = p
+		if i < len(values) {
+			result.Percentiles[i].Amount = &Amount{roundDecimal(decimal.NewFromFloat(values[i]), 2, summaryRounding)}
+		}
+	}
+
+	writeJSON(w, r, http.StatusOK, result)
+}
+
+// parsePercentiles lee "50,90,99"; cada valor debe estar en (0, 100]
+func parsePercentiles(raw string) ([]float64, error) {
+	if raw == "" {
+		return defaultPercentiles, nil
+	}
+	var ps []float64
+	for _, item := range splitList(raw) {
+		p, err := strconv.ParseFloat(item, 64)
+		// NaN no cumple ninguna comparación, así que se descarta aparte junto con ±Inf
+		if err != nil || math.IsNaN(p) || math.IsInf(p, 0) || p <= 0 || p > 100 {
+			return nil, newAPIError(msgInvalidPercentile, item)
+		}
+		ps = append(ps, p)
+	}
+	if len(ps) == 0 {
+		return nil, newAPIError(msgInvalidPercentile, raw)
+	}
+	if len(ps) > maxPercentiles {
+		return nil, newAPIError(msgTooManyPercentiles, maxPercentiles)
+	}
+	return ps, nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParsePercentilesRejectsNonFinite(t *testing.T) {
+	for _, raw := range []string{"NaN", "nan", "Inf", "+Inf", "-Inf", "50,NaN", "0", "100.5"} {
+		if _, err := parsePercentiles(raw); apiErrorKey(err) != msgInvalidPercentile {
+			t.Errorf("%q: error %v, se esperaba %s", raw, err, msgInvalidPercentile)
+		}
+	}
+	ps, err := parsePercentiles("0.5,100")
+	if err != nil || len(ps) != 2 {
+		t.Errorf("0.5,100: %v %v", ps, err)
+	}
+}
+
+func TestSummaryPercentilesNaN(t *testing.T) {
+	newMockDB(t)
+	rec := serve(getSummaryPercentiles, "GET", "/summary/percentiles?p=NaN", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("estado %d, se esperaba 400: %s", rec.Code, rec.Body)
+	}
+}
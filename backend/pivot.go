@@ -0,0 +1,166 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Máximo de columnas (periodos) de /summary/pivot
+const maxPivotPeriods = 60
+
+// Etiqueta de las transacciones sin beneficiario al agrupar por payee
+const noPayeeLabel = "(sin beneficiario)"
+
+// Dimensiones de agrupación admitidas en ?by= y su expresión SQL
+var pivotDimensions = map[string]string{
+	"type":  "type",
+	"payee": "COALESCE(NULLIF(payee, ''), '" + noPayeeLabel + "')",
+}
+
+// Periodos admitidos en ?period= y el formato de su etiqueta
+var pivotPeriodLayouts = map[string]string{
+	"day":   "2006-01-02",
+	"week":  "2006-01-02", // lunes de la semana
+	"month": "2006-01",
+	"year":  "2006",
+}
+
+// PivotRow es una fila de la tabla dinámica: un valor de la dimensión y un total por periodo
+type PivotRow struct {
+	Key   string   `json:"key"`
+	Cells []Amount `json:"cells"`
+	Total Amount   `json:"total"`
+}
+
+// Pivot es la tabla dinámica completa; Cells[i] de cada fila corresponde a Periods[i]
+type Pivot struct {
+	By      string     `json:"by"`
+	Period  string     `json:"period"`
+	Periods []string   `json:"periods"`
+	Rows    []PivotRow `json:"rows"`
+}
+
+// Handler para /summary/pivot (GET: totales por ?by=type|payee y ?period=day|week|month|year).
+// Acepta los mismos filtros que la lista (type, from, to...). Las celdas sin transacciones
+// valen 0 y las filas se ordenan por total descendente. Las celdas suman el importe sin signo,
+// así que para ver el gasto conviene filtrar con ?type=expense.
+func getSummaryPivot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r, "GET")
+		return
+	}
+
+	query := r.URL.Query()
+	by := query.Get("by")
+	if by == "" {
+		by = "type"
+	}
+	dimension, ok := pivotDimensions[by]
+	if !ok {
+		writeError(w, r, http.StatusBadRequest, msgInvalidPivotBy)
+		return
+	}
+	period := query.Get("period")
+	if period == "" {
+		period = "month"
+	}
+	layout, ok := pivotPeriodLayouts[period]
+	if !ok {
+		writeError(w, r, http.StatusBadRequest, msgInvalidPivotPeriod)
+		return
+	}
+
+	filter, err := buildTransactionFilter(query)
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return
+	}
+	// period ya está validado contra la lista de periodos admitidos
+	bucket := "date_trunc('" + period + "', created_at AT TIME ZONE " + filter.arg(appLocation.String()) + ")"
+
+	rows, err := queryReadDB(r.Context(), "summary_pivot",
+		"SELECT "+dimension+", "+bucket+", SUM("+magnitudeSQL()+") FROM transactions"+filter.where()+" GROUP BY 1, 2",
+		filter.args...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	type cell struct {
+		key    string
+		bucket time.Time
+		total  Amount
+	}
+	var cells []cell
+	var first, last time.Time
+	for rows.Next() {
+		var c cell
+		if err := rows.Scan(&c.key, &c.bucket, &c.total); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if first.IsZero() || c.bucket.Before(first) {
+			first = c.bucket
+		}
+		if c.bucket.After(last) {
+			last = c.bucket
+		}
+		cells = append(cells, c)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Columnas continuas entre el primer y el último periodo con datos
+	pivot := Pivot{By: by, Period: period, Periods: []string{}, Rows: []PivotRow{}}
+	index := map[string]int{}
+	if len(cells) > 0 {
+		for p := first; !p.After(last); p = nextPeriod(p, period) {
+			if len(pivot.Periods) == maxPivotPeriods {
+				writeError(w, r, http.StatusBadRequest, msgTooManyPeriods, maxPivotPeriods)
+				return
+			}
+			index[p.Format(layout)] = len(pivot.Periods)
+			pivot.Periods = append(pivot.Periods, p.Format(layout))
+		}
+	}
+
+	byKey := map[string]*PivotRow{}
+	for _, c := range cells {
+		row, ok := byKey[c.key]
+		if !ok {
+			row = &PivotRow{Key: c.key, Cells: make([]Amount, len(pivot.Periods))}
+			byKey[c.key] = row
+		}
+		i := index[c.bucket.Format(layout)]
+		row.Cells[i] = row.Cells[i].Add(c.total)
+		row.Total = row.Total.Add(c.total)
+	}
+	for _, row := range byKey {
+		pivot.Rows = append(pivot.Rows, *row)
+	}
+	sort.Slice(pivot.Rows, func(i, j int) bool {
+		if c := pivot.Rows[i].Total.Cmp(pivot.Rows[j].Total.Decimal); c != 0 {
+			return c > 0
+		}
+		return pivot.Rows[i].Key < pivot.Rows[j].Key
+	})
+
+	writeJSON(w, r, http.StatusOK, pivot)
+}
+
+// nextPeriod avanza al inicio del periodo siguiente
+func nextPeriod(t time.Time, period string) time.Time {
+	switch period {
+	case "day":
+		return t.AddDate(0, 0, 1)
+	case "week":
+		return t.AddDate(0, 0, 7)
+	case "year":
+		return t.AddDate(1, 0, 0)
+	}
+	return t.AddDate(0, 1, 0)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Handler para /transaction/{id}/receipt.pdf (GET: recibo imprimible de una transacción)
+func getTransactionReceipt(w http.ResponseWriter, r *http.Request, id int) {
+	t, err := fetchTransaction(r.Context(), id)
+	if err == sql.ErrNoRows {
+		writeError(w, r, http.StatusNotFound, msgNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	pdf := renderReceipt(t)
+	if err := pdf.Error(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="recibo-transaccion-%d.pdf"`, t.ID))
+	if err := pdf.Output(w); err != nil {
+		log.Printf("Error al enviar el recibo de la transacción %d: %v", t.ID, err)
+	}
+}
+
+// renderReceipt compone un recibo A4 de una página con los datos de la transacción
+func renderReceipt(t Transaction) *gofpdf.Fpdf {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	// Las fuentes base del PDF usan cp1252: se traducen los textos UTF-8 (acentos, ñ, €)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle(tr("Recibo de transacción #"+strconv.Itoa(t.ID)), false)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 12, tr("Recibo de transacción"), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(110, 110, 110)
+	pdf.CellFormat(0, 6, tr("Nº "+strconv.Itoa(t.ID)), "", 1, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(6)
+
+	typeLabel := "Gasto"
+	if t.Type == "income" {
+		typeLabel = "Ingreso"
+	}
+	payee := "-"
+	if t.Payee != nil {
+		payee = *t.Payee
+	}
+	cleared := "No"
+	if t.Cleared {
+		cleared = "Sí"
+	}
+	rows := [][2]string{
+		{"Fecha", t.CreatedAt.In(appLocation).Format("02/01/2006 15:04")},
+		{"Descripción", t.Description},
+		{"Beneficiario", payee},
+		{"Tipo", typeLabel},
+		{"Origen", t.Source},
+		{"Conciliada", cleared},
+	}
+	for _, row := range rows {
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.CellFormat(45, 9, tr(row[0]), "B", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.MultiCell(0, 9, tr(row[1]), "B", "L", false)
+	}
+
+	pdf.Ln(8)
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(45, 12, "Importe", "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 12, t.Amount.String(), "", 1, "R", false, 0, "")
+	return pdf
+}
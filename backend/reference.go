@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Las referencias externas (número de factura...) no se pueden repetir entre transacciones;
+// el índice es parcial para que las transacciones sin referencia no choquen entre sí
+const createReferenceIndexSQL = `
+	CREATE UNIQUE INDEX IF NOT EXISTS transactions_reference_key
+	ON transactions (reference) WHERE reference IS NOT NULL`
+
+// normalizeReference recorta los espacios de la referencia; si queda vacía devuelve nil (NULL)
+func normalizeReference(ref *string) *string {
+	if ref == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*ref)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+// isReferenceConflict indica si err es la violación del índice único de reference
+func isReferenceConflict(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" &&
+		pqErr.Constraint == "transactions_reference_key"
+}
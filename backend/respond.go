@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+)
+
+// Límite por defecto del cuerpo de las peticiones de escritura (MAX_BODY_BYTES)
+var maxBodyBytes int64 = 64 << 10
+
+// writeJSON escribe v como JSON con el código de estado indicado.
+// Con ?pretty=true o la cabecera "X-Pretty: true" la salida se indenta para leerla con curl.
+//
+// Contrato de la API: las colecciones vacías se serializan siempre como [] (nunca null) y un
+// recurso individual que no existe responde 404 en lugar de un cuerpo null. Los handlers
+// inicializan sus listas con []T{}; como red de seguridad, un slice nil se escribe como [].
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice && rv.IsNil() {
+		v = []any{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	if wantsPretty(r) {
+		enc.SetIndent("", "  ")
+	}
+	enc.Encode(v)
+}
+
+func wantsPretty(r *http.Request) bool {
+	return r.URL.Query().Get("pretty") == "true" || r.Header.Get("X-Pretty") == "true"
+}
+
+// decodeJSON lee el cuerpo JSON de la petición en v sin superar limit bytes.
+// Si falla responde 413 (cuerpo demasiado grande) o 400 y devuelve false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, limit int64, v any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil {
+		return true
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeJSON(w, r, http.StatusRequestEntityTooLarge, map[string]string{
+			"error": localize(r, msgBodyTooLarge, tooLarge.Limit),
+		})
+		return false
+	}
+	http.Error(w, errorText(r, err), http.StatusBadRequest)
+	return false
+}
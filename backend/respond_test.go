@@ -0,0 +1,86 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestDecodeJSONBodyLimitBoundary(t *testing.T) {
+	body := `{"description":"Café","amount":3.5,"type":"expense"}`
+	cases := []struct {
+		limit  int64
+		ok     bool
+		status int
+	}{
+		{int64(len(body)), true, http.StatusOK},
+		{int64(len(body)) - 1, false, http.StatusRequestEntityTooLarge},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/transaction", strings.NewReader(body))
+		var tx Transaction
+		ok := decodeJSON(rec, r, c.limit, &tx)
+		if ok != c.ok || rec.Code != c.status {
+			t.Errorf("límite %d para %d bytes: ok=%t estado=%d, se esperaba ok=%t estado=%d",
+				c.limit, len(body), ok, rec.Code, c.ok, c.status)
+		}
+		if !c.ok && !strings.Contains(rec.Body.String(), strconv.FormatInt(c.limit, 10)) {
+			t.Errorf("el 413 no indica el límite: %s", rec.Body)
+		}
+	}
+}
+
+func TestWriteJSONNilSliceIsEmptyArray(t *testing.T) {
+	var transactions []Transaction
+	rec := httptest.NewRecorder()
+	writeJSON(rec, httptest.NewRequest("GET", "/transactions", nil), http.StatusOK, transactions)
+	if got := strings.TrimSpace(rec.Body.String()); got != "[]" {
+		t.Errorf("slice nil serializado como %s, se esperaba []", got)
+	}
+}
+
+func TestEmptyListIsEmptyArray(t *testing.T) {
+	mock := newMockDB(t)
+	mock.ExpectQuery("SELECT .+ FROM transactions").WillReturnRows(transactionRows())
+	mock.ExpectQuery("FROM pg_class").WillReturnRows(sqlmock.NewRows([]string{"reltuples"}).AddRow(0))
+
+	rec := serve(handleTransactions, "GET", "/transactions", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+	}
+	if got := strings.TrimSpace(rec.Body.String()); got != "[]" {
+		t.Errorf("lista vacía serializada como %s, se esperaba []", got)
+	}
+}
+
+func TestMissingTransactionIsNotFound(t *testing.T) {
+	mock := newMockDB(t)
+	mock.ExpectQuery("SELECT .+ FROM transactions WHERE id = \\$1").WithArgs(99).WillReturnError(sql.ErrNoRows)
+
+	rec := serve(handleTransactionByID, "GET", "/transaction/99", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("estado %d, se esperaba 404", rec.Code)
+	}
+	if strings.TrimSpace(rec.Body.String()) == "null" {
+		t.Error("un recurso que no existe no debe responder null")
+	}
+}
+
+func TestEmptyPayeeSummaryIsEmptyArray(t *testing.T) {
+	mock := newMockDB(t)
+	mock.ExpectQuery("SELECT .+ FROM transactions").WillReturnRows(sqlmock.NewRows([]string{"payee", "total", "count"}))
+
+	rec := serve(getSummaryByPayee, "GET", "/summary/by-payee", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+	}
+	if got := strings.TrimSpace(rec.Body.String()); got != "[]" {
+		t.Errorf("resumen vacío serializado como %s, se esperaba []", got)
+	}
+}
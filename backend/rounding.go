@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/shopspring/decimal"
+)
+
+// roundingMode es la estrategia de redondeo de los valores derivados de los resúmenes
+type roundingMode int
+
+const (
+	roundHalfUp   roundingMode = iota // 0.125 -> 0.13 (el 5 se aleja del cero)
+	roundHalfEven                     // 0.125 -> 0.12 (redondeo bancario: el 5 va al par)
+)
+
+// Redondeo aplicado a los valores calculados de los resúmenes (SUMMARY_ROUNDING=half-up|bankers).
+// Afecta a: savings_rate de /summary y a los importes medios de /forecast.
+// Los totales (income, expense, balance) son sumas exactas de NUMERIC y no se redondean.
+var summaryRounding = roundHalfUp
+
+// Decimales de las proporciones derivadas (RATIO_PRECISION): savings_rate de /summary y
+// score de /transaction/{id}/similar. Evita colas como 0.33333333333333331 en el JSON.
+var ratioPlaces int32 = 4
+
+// Máximo de RATIO_PRECISION: más decimales que los de un float64 no aportan nada
+const maxRatioPlaces = 15
+
+// loadRatioPrecision lee RATIO_PRECISION (0 a maxRatioPlaces)
+func loadRatioPrecision() {
+	v := os.Getenv("RATIO_PRECISION")
+	if v == "" {
+		return
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 || n > maxRatioPlaces {
+		log.Fatalf("RATIO_PRECISION inválido: %q (usa un entero entre 0 y %d)", v, maxRatioPlaces)
+	}
+	ratioPlaces = int32(n)
+}
+
+// roundRatio redondea una proporción a ratioPlaces decimales con el modo de summaryRounding
+func roundRatio(v decimal.Decimal) float64 {
+	return roundDecimal(v, ratioPlaces, summaryRounding).InexactFloat64()
+}
+
+func parseRoundingMode(s string) (roundingMode, error) {
+	switch s {
+	case "", "half-up":
+		return roundHalfUp, nil
+	case "bankers", "half-even":
+		return roundHalfEven, nil
+	}
+	return 0, fmt.Errorf("modo de redondeo desconocido %q (usa half-up o bankers)", s)
+}
+
+// roundDecimal redondea v a places decimales según mode. Al operar sobre decimales exactos,
+// 0.125 es de verdad 0.125 y no su aproximación binaria.
+func roundDecimal(v decimal.Decimal, places int32, mode roundingMode) decimal.Decimal {
+	if mode == roundHalfEven {
+		return v.RoundBank(places)
+	}
+	return v.Round(places)
+}
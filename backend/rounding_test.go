@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRoundDecimalModes(t *testing.T) {
+	cases := []struct {
+		value string
+		mode  roundingMode
+		want  string
+	}{
+		{"0.125", roundHalfUp, "0.13"},
+		{"0.125", roundHalfEven, "0.12"},
+		{"0.135", roundHalfUp, "0.14"},
+		{"0.135", roundHalfEven, "0.14"},
+		{"-0.125", roundHalfUp, "-0.13"},
+		{"-0.125", roundHalfEven, "-0.12"},
+	}
+	for _, c := range cases {
+		got := roundDecimal(decimal.RequireFromString(c.value), 2, c.mode)
+		if got.String() != c.want {
+			t.Errorf("roundDecimal(%s, modo %d) = %s, se esperaba %s", c.value, c.mode, got, c.want)
+		}
+	}
+}
+
+func TestParseRoundingMode(t *testing.T) {
+	for input, want := range map[string]roundingMode{"": roundHalfUp, "half-up": roundHalfUp, "bankers": roundHalfEven, "half-even": roundHalfEven} {
+		got, err := parseRoundingMode(input)
+		if err != nil || got != want {
+			t.Errorf("parseRoundingMode(%q) = %d, %v", input, got, err)
+		}
+	}
+	if _, err := parseRoundingMode("down"); err == nil {
+		t.Error("parseRoundingMode(\"down\"): se esperaba un error")
+	}
+}
+
+func TestRatioPrecision(t *testing.T) {
+	defer func() { ratioPlaces = 4 }()
+
+	cases := []struct {
+		env  string
+		want float64 // savings_rate de 100 de ingresos y 66.67 de gastos
+	}{
+		{"", 0.3333},
+		{"2", 0.33},
+		{"0", 0},
+		{"6", 0.3333},
+	}
+	for _, c := range cases {
+		ratioPlaces = 4
+		t.Setenv("RATIO_PRECISION", c.env)
+		loadRatioPrecision()
+		rate := savingsRate(mustAmount(t, "100"), mustAmount(t, "66.67"))
+		if rate == nil || *rate != c.want {
+			t.Errorf("RATIO_PRECISION=%q: savings_rate %v, se esperaba %v", c.env, rate, c.want)
+		}
+	}
+
+	// Una división periódica no deja una cola de decimales en el JSON
+	ratioPlaces = 4
+	if got := roundRatio(decimal.NewFromInt(1).Div(decimal.NewFromInt(3))); got != 0.3333 {
+		t.Errorf("roundRatio(1/3) = %v, se esperaba 0.3333", got)
+	}
+	if savingsRate(Amount{}, mustAmount(t, "10")) != nil {
+		t.Error("sin ingresos savings_rate debe ser null")
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// route asocia un patrón del mux con su handler y los métodos HTTP que admite
+type route struct {
+	pattern string
+	methods []string
+	handler http.HandlerFunc
+}
+
+// subtreeMethods resuelve, para los patrones de subárbol (acabados en /), los métodos de la
+// ruta concreta pedida; false si esa ruta no existe. Su methods en la tabla es la unión.
+var subtreeMethods = map[string]func(path string) ([]string, bool){
+	"/transaction/": transactionPathMethods,
+}
+
+// apiRoutes es la tabla de rutas de la API
+func apiRoutes() []route {
+	return []route{
+		{"/transactions", []string{"GET", "DELETE"}, handleTransactions},
+		{"/transactions/recent", []string{"GET"}, getRecentTransactions},
+		{"/transactions/changes", []string{"GET"}, getTransactionChanges},
+		{"/transactions/anomalies", []string{"GET"}, getTransactionAnomalies},
+		{"/transactions/date-range", []string{"GET"}, getTransactionDateRange},
+		{"/transactions/archive", []string{"POST"}, archiveTransactions},
+		{"/transactions/import", []string{"POST"}, importTransactions},
+		{"/transactions/import/validate", []string{"POST"}, validateImport},
+		{"/transactions/bulk-upsert", []string{"POST"}, bulkUpsertTransactions},
+		{"/transaction", []string{"POST"}, createTransaction},
+		{"/transaction/", []string{"GET", "POST", "PUT", "PATCH", "DELETE"}, handleTransactionByID},
+		{"/templates", []string{"GET"}, getTemplates},
+		{"/payees", []string{"GET"}, getPayees},
+		{"/schema/transaction", []string{"GET"}, getTransactionSchema},
+		{"/summary", []string{"GET"}, getSummary},
+		{"/summary/balance-series", []string{"GET"}, getBalanceSeries},
+		{"/summary/pivot", []string{"GET"}, getSummaryPivot},
+		{"/summary/by-payee", []string{"GET"}, getSummaryByPayee},
+		{"/summary/monthly", []string{"GET"}, getMonthlySummary},
+		{"/summary/percentiles", []string{"GET"}, getSummaryPercentiles},
+		{"/summary/monthly.csv", []string{"GET"}, getMonthlySummaryCSV},
+		{"/forecast", []string{"GET"}, getForecast},
+		{"/healthz", []string{"GET"}, getHealthz},
+		{"/readyz", []string{"GET"}, getReadyz},
+		{"/debug/db", []string{"GET"}, requireDebugKey(getDebugDB)},
+		{"/debug/selfcheck", []string{"GET"}, requireDebugKey(getDebugSelfCheck)},
+	}
+}
+
+// allow devuelve el valor de la cabecera Allow de la ruta (incluye OPTIONS)
+func (rt route) allow() string {
+	return allowHeader(rt.methods)
+}
+
+func allowHeader(methods []string) string {
+	return strings.Join(methods, ", ") + ", OPTIONS"
+}
+
+// ServeHTTP responde a OPTIONS con los métodos reales de la ruta, rechaza con 405 los que
+// no admite y delega el resto en el handler.
+// Las rutas que no están en la tabla no llegan aquí: el mux responde 404. En los subárboles,
+// OPTIONS sobre una ruta que subtreeMethods no reconoce también responde 404; los demás métodos
+// llegan al handler, que decide entre 400 y 404.
+func (rt route) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	methods, known := rt.methods, true
+	if resolve, ok := subtreeMethods[rt.pattern]; ok {
+		methods, known = resolve(r.URL.Path)
+	}
+	if r.Method == "OPTIONS" {
+		if !known {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Allow", allowHeader(methods))
+		w.Header().Set("Access-Control-Allow-Methods", allowHeader(methods))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if known && !slices.Contains(methods, r.Method) {
+		methodNotAllowed(w, r, methods...)
+		return
+	}
+	rt.handler(w, r)
+}
+
+// methodNotAllowed responde 405 con la cabecera Allow y, para los clientes SPA, los mismos
+// métodos en el cuerpo JSON
+func methodNotAllowed(w http.ResponseWriter, r *http.Request, methods ...string) {
+	allowed := append(slices.Clone(methods), "OPTIONS")
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeJSON(w, r, http.StatusMethodNotAllowed, map[string]any{
+		"error": localize(r, msgMethodNotAllowed),
+		"allow": allowed,
+	})
+}
+
+// registerRoutes registra todas las rutas de la tabla en el mux, envueltas con CORS
+func registerRoutes(mux *http.ServeMux) {
+	for _, rt := range apiRoutes() {
+		mux.Handle(rt.pattern, corsHandler(rt, rt.allow()))
+	}
+}
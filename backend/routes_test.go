@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOptionsTransactionSubtree(t *testing.T) {
+	mux := http.NewServeMux()
+	registerRoutes(mux)
+
+	cases := []struct {
+		path   string
+		status int
+		allow  string
+	}{
+		{"/transaction/42", http.StatusOK, "GET, PUT, DELETE, OPTIONS"},
+		{"/transaction/42/", http.StatusOK, "GET, PUT, DELETE, OPTIONS"},
+		{"/transaction/42/split", http.StatusOK, "POST, OPTIONS"},
+		{"/transaction/42/cleared", http.StatusOK, "PATCH, OPTIONS"},
+		{"/transaction/42/bogus", http.StatusNotFound, ""},
+		{"/transaction/abc", http.StatusNotFound, ""},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("OPTIONS", c.path, nil))
+		if rec.Code != c.status {
+			t.Errorf("OPTIONS %s: estado %d, se esperaba %d", c.path, rec.Code, c.status)
+		}
+		if got := rec.Header().Get("Allow"); got != c.allow {
+			t.Errorf("OPTIONS %s: Allow %q, se esperaba %q", c.path, got, c.allow)
+		}
+	}
+}
+
+func TestTransactionSubtreeRejectsOtherMethods(t *testing.T) {
+	mux := http.NewServeMux()
+	registerRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/transaction/42", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST /transaction/42: estado %d, se esperaba 405", rec.Code)
+	}
+	if got := rec.Header().Get("Allow"); got != "GET, PUT, DELETE, OPTIONS" {
+		t.Errorf("POST /transaction/42: Allow %q", got)
+	}
+}
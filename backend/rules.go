@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// transactionRule es una regla de negocio que se evalúa al crear o actualizar una transacción,
+// después de la validación básica. Devuelve un apiError si la transacción la incumple.
+type transactionRule func(Transaction) error
+
+// transactionRules son las reglas activas. loadRules añade las integradas que estén
+// configuradas; otras reglas se registran añadiéndolas a este slice.
+var transactionRules []transactionRule
+
+// loadRules configura las reglas integradas a partir de las variables de entorno:
+//   - RULE_MAX_AMOUNT_WITHOUT_PAYEE: importe máximo de un gasto sin beneficiario
+//   - RULE_DISALLOWED_PAYEES: beneficiarios no admitidos, separados por comas
+//   - RULE_MAX_DESCRIPTION_LENGTH: longitud máxima de la descripción, en caracteres
+//   - RULE_MAX_DESCRIPTION_LENGTH_INCOME / _EXPENSE: la misma longitud para un tipo concreto;
+//     el tipo que no la tenga usa la global
+func loadRules() {
+	if v := os.Getenv("RULE_MAX_AMOUNT_WITHOUT_PAYEE"); v != "" {
+		limit, err := newAmount(v)
+		if err != nil || !limit.IsPositive() {
+			log.Fatalf("RULE_MAX_AMOUNT_WITHOUT_PAYEE inválido: %q", v)
+		}
+		transactionRules = append(transactionRules, maxAmountWithoutPayeeRule(limit))
+	}
+	if payees := splitList(os.Getenv("RULE_DISALLOWED_PAYEES")); len(payees) > 0 {
+		transactionRules = append(transactionRules, disallowedPayeesRule(payees))
+	}
+	global := positiveIntEnv("RULE_MAX_DESCRIPTION_LENGTH", 0)
+	limits := map[string]int{}
+	for _, typ := range []string{"income", "expense"} {
+		if limit := positiveIntEnv("RULE_MAX_DESCRIPTION_LENGTH_"+strings.ToUpper(typ), global); limit > 0 {
+			limits[typ] = limit
+		}
+	}
+	if len(limits) > 0 {
+		transactionRules = append(transactionRules, maxDescriptionLengthRule(limits))
+	}
+}
+
+// maxAmountWithoutPayeeRule exige beneficiario en los gastos que superen limit
+func maxAmountWithoutPayeeRule(limit Amount) transactionRule {
+	return func(t Transaction) error {
+		if t.Type == "expense" && t.Payee == nil && t.Amount.GreaterThan(limit.Decimal) {
+			return newAPIError(msgRulePayeeRequired, limit)
+		}
+		return nil
+	}
+}
+
+// maxDescriptionLengthRule limita la descripción según el tipo; los tipos sin límite no se
+// comprueban. Se cuentan caracteres y no bytes, para no penalizar las tildes.
+func maxDescriptionLengthRule(limits map[string]int) transactionRule {
+	return func(t Transaction) error {
+		limit, ok := limits[t.Type]
+		if ok && utf8.RuneCountInString(t.Description) > limit {
+			return newAPIError(msgRuleDescriptionTooLong, limit)
+		}
+		return nil
+	}
+}
+
+// disallowedPayeesRule rechaza los beneficiarios de la lista (sin distinguir mayúsculas)
+func disallowedPayeesRule(payees []string) transactionRule {
+	return func(t Transaction) error {
+		if t.Payee == nil {
+			return nil
+		}
+		for _, p := range payees {
+			if strings.EqualFold(*t.Payee, p) {
+				return newAPIError(msgRuleDisallowedPayee, *t.Payee)
+			}
+		}
+		return nil
+	}
+}
+
+// checkRules evalúa todas las reglas y devuelve los incumplimientos traducidos al idioma
+// de la petición (vacío si la transacción las cumple todas)
+func checkRules(r *http.Request, t Transaction) []string {
+	violations := []string{}
+	for _, rule := range transactionRules {
+		if err := rule(t); err != nil {
+			violations = append(violations, errorText(r, err))
+		}
+	}
+	return violations
+}
+
+// enforceRules responde 422 con todos los incumplimientos juntos y devuelve false si la
+// transacción incumple alguna regla
+func enforceRules(w http.ResponseWriter, r *http.Request, t Transaction) bool {
+	violations := checkRules(r, t)
+	if len(violations) == 0 {
+		return true
+	}
+	writeJSON(w, r, http.StatusUnprocessableEntity, map[string]any{
+		"error":      localize(r, msgRuleViolations),
+		"violations": violations,
+	})
+	return false
+}
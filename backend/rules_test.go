@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestMaxDescriptionLengthPerType(t *testing.T) {
+	defer func() { transactionRules = nil }()
+
+	cases := []struct {
+		global, income, expense string
+		typ, description        string
+		wantViolation           bool
+	}{
+		// El límite de income sustituye al global; expense usa el global
+		{"10", "5", "", "income", "Nómina", true},
+		{"10", "5", "", "income", "Bono", false},
+		{"10", "5", "", "expense", "Supermercado", true},
+		{"10", "5", "", "expense", "Panadería", false},
+		// Sin global, el tipo sin límite propio no se comprueba
+		{"", "", "5", "income", "Nómina de marzo", false},
+		{"", "", "5", "expense", "Panadería", true},
+		// Los caracteres con tilde cuentan como uno
+		{"", "", "5", "expense", "Cañón", false},
+	}
+	for _, c := range cases {
+		transactionRules = nil
+		t.Setenv("RULE_MAX_DESCRIPTION_LENGTH", c.global)
+		t.Setenv("RULE_MAX_DESCRIPTION_LENGTH_INCOME", c.income)
+		t.Setenv("RULE_MAX_DESCRIPTION_LENGTH_EXPENSE", c.expense)
+		loadRules()
+
+		var violation error
+		for _, rule := range transactionRules {
+			if err := rule(Transaction{Type: c.typ, Description: c.description}); err != nil {
+				violation = err
+			}
+		}
+		if (violation != nil) != c.wantViolation {
+			t.Errorf("global=%q income=%q expense=%q, %s %q: incumplimiento %v", c.global, c.income, c.expense, c.typ, c.description, violation)
+		}
+		if violation != nil && apiErrorKey(violation) != msgRuleDescriptionTooLong {
+			t.Errorf("%s %q: error %v, se esperaba %s", c.typ, c.description, violation, msgRuleDescriptionTooLong)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"log"
+	"strings"
+)
+
+// columnDef describe una columna de la tabla de transacciones
+type columnDef struct {
+	name        string
+	sqlType     string
+	constraints string // NOT NULL, DEFAULT...
+	liveOnly    string // FOREIGN KEY, UNIQUE...: solo en transactions, el archivo no las lleva
+}
+
+// Columnas esperadas de transactions, en orden. Es la única fuente de verdad del esquema:
+// de aquí salen el CREATE TABLE y la detección de columnas que faltan en tablas antiguas.
+var transactionColumnDefs = []columnDef{
+	{"id", "SERIAL", "PRIMARY KEY", ""},
+	{"description", "TEXT", "NOT NULL", ""},
+	{"amount", "NUMERIC(10, 2)", "NOT NULL", ""},
+	{"type", "VARCHAR(10)", "NOT NULL", ""},
+	{"created_at", "TIMESTAMP WITH TIME ZONE", "DEFAULT CURRENT_TIMESTAMP", ""},
+	{"source", "TEXT", "NOT NULL DEFAULT 'manual'", ""},
+	{"cleared", "BOOLEAN", "NOT NULL DEFAULT false", ""},
+	{"parent_id", "INTEGER", "", "REFERENCES transactions(id) ON DELETE SET NULL"},
+	{"is_split", "BOOLEAN", "NOT NULL DEFAULT false", ""},
+	{"payee", "TEXT", "", ""},
+	{"is_template", "BOOLEAN", "NOT NULL DEFAULT false", ""},
+	{"uuid", "UUID", "", "UNIQUE"},
+	{"status", "VARCHAR(10)", "NOT NULL DEFAULT 'posted'", ""},
+	{"reference", "TEXT", "", ""},
+	{"updated_at", "TIMESTAMP WITH TIME ZONE", "NOT NULL DEFAULT CURRENT_TIMESTAMP", ""},
+	{"content_hash", "TEXT", "", ""},
+	{"raw_source", "TEXT", "", ""},
+}
+
+// definition devuelve la definición SQL de la columna, con o sin las restricciones que
+// solo tienen sentido en la tabla viva
+func (c columnDef) definition(live bool) string {
+	def := c.name + " " + c.sqlType
+	if c.constraints != "" {
+		def += " " + c.constraints
+	}
+	if live && c.liveOnly != "" {
+		def += " " + c.liveOnly
+	}
+	return def
+}
+
+// createTableSQL genera el CREATE TABLE de transactions a partir de transactionColumnDefs
+func createTableSQL() string {
+	defs := make([]string, len(transactionColumnDefs))
+	for i, c := range transactionColumnDefs {
+		defs[i] = c.definition(true)
+	}
+	return "CREATE TABLE IF NOT EXISTS transactions (\n\t" + strings.Join(defs, ",\n\t") + "\n)"
+}
+
+// Tabla de archivo: misma estructura que transactions más la fecha de archivado
+const createArchiveTableSQL = `
+	CREATE TABLE IF NOT EXISTS transactions_archive (
+		LIKE transactions,
+		archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+
+// ensureSchema crea las tablas de transacciones y les añade las columnas que falten
+func ensureSchema() error {
+	if _, err := db.Exec(createTableSQL()); err != nil {
+		return err
+	}
+	if err := addMissingColumns("transactions", true); err != nil {
+		return err
+	}
+	if _, err := db.Exec(createArchiveTableSQL); err != nil {
+		return err
+	}
+	// El archivo se creó con LIKE en su momento: las columnas posteriores también le faltan
+	if err := addMissingColumns("transactions_archive", false); err != nil {
+		return err
+	}
+	if err := migrateAmountSigns(); err != nil {
+		return err
+	}
+	if _, err := db.Exec(createSnapshotsTableSQL); err != nil {
+		return err
+	}
+	if _, err := db.Exec(createReferenceIndexSQL); err != nil {
+		return err
+	}
+	if _, err := db.Exec(createUpdatedAtIndexSQL); err != nil {
+		return err
+	}
+	if _, err := db.Exec(createDeletionsSQL); err != nil {
+		return err
+	}
+	if _, err := db.Exec(createContentHashIndexSQL); err != nil {
+		return err
+	}
+	enableTrigram()
+	return nil
+}
+
+// addMissingColumns compara information_schema.columns con transactionColumnDefs y añade
+// con ALTER TABLE las columnas esperadas que no existan. En el archivo (live false) se
+// añaden sin claves foráneas ni UNIQUE, igual que las que copió LIKE: sus filas apuntan a
+// transacciones ya archivadas y no deben chocar con índices de la tabla viva.
+func addMissingColumns(table string, live bool) error {
+	rows, err := db.Query(
+		"SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1",
+		table)
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range transactionColumnDefs {
+		if existing[c.name] {
+			continue
+		}
+		def := c.definition(live)
+		if _, err := db.Exec("ALTER TABLE " + table + " ADD COLUMN IF NOT EXISTS " + def); err != nil {
+			return err
+		}
+		log.Printf("Columna añadida a %s: %s", table, def)
+	}
+	return nil
+}
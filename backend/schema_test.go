@@ -0,0 +1,53 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// expectMissingUUID simula una tabla a la que solo le falta la columna uuid
+func expectMissingUUID(mock sqlmock.Sqlmock, table string) {
+	rows := sqlmock.NewRows([]string{"column_name"})
+	for _, c := range transactionColumnDefs {
+		if c.name != "uuid" {
+			rows.AddRow(c.name)
+		}
+	}
+	mock.ExpectQuery("SELECT column_name FROM information_schema.columns").
+		WithArgs(table).
+		WillReturnRows(rows)
+}
+
+func TestAddMissingColumnsKeepsUniqueOnLiveTable(t *testing.T) {
+	mock := newMockDB(t)
+	expectMissingUUID(mock, "transactions")
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE transactions ADD COLUMN IF NOT EXISTS uuid UUID UNIQUE")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := addMissingColumns("transactions", true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAddMissingColumnsArchiveWithoutUnique(t *testing.T) {
+	mock := newMockDB(t)
+	expectMissingUUID(mock, "transactions_archive")
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE transactions_archive ADD COLUMN IF NOT EXISTS uuid UUID") + "$").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := addMissingColumns("transactions_archive", false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateTableSQLKeepsLiveConstraints(t *testing.T) {
+	sql := createTableSQL()
+	for _, want := range []string{"uuid UUID UNIQUE", "parent_id INTEGER REFERENCES transactions(id) ON DELETE SET NULL"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("falta %q en:\n%s", want, sql)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Tablas cuya estructura sigue transactionColumnDefs
+var selfCheckTables = []string{"transactions", "transactions_archive"}
+
+// SelfCheck es el resultado de comprobar la base de datos contra el esquema esperado
+type SelfCheck struct {
+	OK        bool             `json:"ok"`
+	CheckedAt time.Time        `json:"checked_at"`
+	Rows      map[string]int64 `json:"rows"`     // filas de cada tabla
+	Problems  []string         `json:"problems"` // columnas que faltan o tienen otro tipo
+}
+
+// expectedDataType traduce el tipo de columnDef al data_type de information_schema:
+// SERIAL es un integer con secuencia y los tipos con precisión pierden el paréntesis
+func expectedDataType(sqlType string) string {
+	base, _, _ := strings.Cut(sqlType, "(")
+	switch base = strings.ToLower(strings.TrimSpace(base)); base {
+	case "serial":
+		return "integer"
+	case "varchar":
+		return "character varying"
+	}
+	return base
+}
+
+// runSelfCheck comprueba que las tablas de transacciones tienen todas las columnas de
+// transactionColumnDefs con el tipo esperado y cuenta sus filas. Las columnas de más no son
+// un problema: las puede haber añadido otra versión o una herramienta externa.
+func runSelfCheck(ctx context.Context) (SelfCheck, error) {
+	check := SelfCheck{CheckedAt: appNow(), Rows: map[string]int64{}, Problems: []string{}}
+	for _, table := range selfCheckTables {
+		rows, err := queryDB(ctx, "selfcheck_columns",
+			"SELECT column_name, data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1",
+			table)
+		if err != nil {
+			return check, err
+		}
+		actual := map[string]string{}
+		for rows.Next() {
+			var name, dataType string
+			if err := rows.Scan(&name, &dataType); err != nil {
+				rows.Close()
+				return check, err
+			}
+			actual[name] = dataType
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return check, err
+		}
+		if len(actual) == 0 {
+			check.Problems = append(check.Problems, fmt.Sprintf("%s: la tabla no existe", table))
+			continue
+		}
+		for _, c := range transactionColumnDefs {
+			want := expectedDataType(c.sqlType)
+			got, ok := actual[c.name]
+			switch {
+			case !ok:
+				check.Problems = append(check.Problems, fmt.Sprintf("%s.%s: falta la columna (%s)", table, c.name, want))
+			case got != want:
+				check.Problems = append(check.Problems, fmt.Sprintf("%s.%s: tipo %s, se esperaba %s", table, c.name, got, want))
+			}
+		}
+
+		// table sale de selfCheckTables, no de la petición
+		var count int64
+		if err := queryRowDB(ctx, "selfcheck_count", "SELECT COUNT(*) FROM "+table).Scan(&count); err != nil {
+			return check, err
+		}
+		check.Rows[table] = count
+	}
+	check.OK = len(check.Problems) == 0
+	return check, nil
+}
+
+// startupSelfCheck ejecuta la comprobación al arrancar y detiene el servidor si la base de
+// datos no es compatible, antes de que las peticiones empiecen a fallar una a una
+func startupSelfCheck() {
+	check, err := runSelfCheck(context.Background())
+	if err != nil {
+		log.Fatalf("Error en la comprobación de arranque: %v", err)
+	}
+	if !check.OK {
+		log.Fatalf("La base de datos no tiene el esquema esperado:\n\t%s", strings.Join(check.Problems, "\n\t"))
+	}
+	for _, table := range selfCheckTables {
+		log.Printf("Comprobación de arranque: %s tiene %d filas", table, check.Rows[table])
+	}
+}
+
+// Handler para /debug/selfcheck (GET: repite la comprobación de arranque; 503 si falla)
+func getDebugSelfCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r, "GET")
+		return
+	}
+
+	check, err := runSelfCheck(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	status := http.StatusOK
+	if !check.OK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, r, status, check)
+}
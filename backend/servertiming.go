@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Añadir la cabecera Server-Timing con el tiempo de base de datos y el total (SERVER_TIMING=true).
+// Desactivado por defecto: expone detalles internos que en producción no hacen falta.
+var serverTimingEnabled = false
+
+// requestTiming acumula el tiempo pasado en consultas durante una petición
+type requestTiming struct {
+	dbNanos atomic.Int64
+	queries atomic.Int64
+}
+
+type requestTimingKey struct{}
+
+// addQueryTiming suma la duración de una consulta a la petición del contexto, si se mide
+func addQueryTiming(ctx context.Context, elapsed time.Duration) {
+	if timing, ok := ctx.Value(requestTimingKey{}).(*requestTiming); ok {
+		timing.dbNanos.Add(int64(elapsed))
+		timing.queries.Add(1)
+	}
+}
+
+// serverTimingWriter escribe Server-Timing justo antes de la cabecera de la respuesta, que
+// es el último momento en que se pueden añadir cabeceras
+type serverTimingWriter struct {
+	http.ResponseWriter
+	start       time.Time
+	timing      *requestTiming
+	wroteHeader bool
+}
+
+func (sw *serverTimingWriter) WriteHeader(status int) {
+	if !sw.wroteHeader {
+		sw.wroteHeader = true
+		sw.Header().Set("Server-Timing", fmt.Sprintf(`db;dur=%.1f;desc="%d consultas", total;dur=%.1f`,
+			milliseconds(time.Duration(sw.timing.dbNanos.Load())), sw.timing.queries.Load(), milliseconds(time.Since(sw.start))))
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *serverTimingWriter) Write(b []byte) (int, error) {
+	if !sw.wroteHeader {
+		sw.WriteHeader(http.StatusOK)
+	}
+	return sw.ResponseWriter.Write(b)
+}
+
+// Unwrap da acceso al ResponseWriter original (http.ResponseController lo usa para Flush)
+func (sw *serverTimingWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// milliseconds convierte una duración a milisegundos con decimales, como espera Server-Timing
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// serverTimingHandler mide el tiempo de base de datos y el total de cada petición y los
+// devuelve en Server-Timing, que las herramientas de desarrollo del navegador muestran
+// desglosado. El total es el transcurrido hasta que se escribe la cabecera.
+func serverTimingHandler(h http.Handler) http.Handler {
+	if !serverTimingEnabled {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timing := &requestTiming{}
+		sw := &serverTimingWriter{ResponseWriter: w, start: time.Now(), timing: timing}
+		ctx := context.WithValue(r.Context(), requestTimingKey{}, timing)
+		h.ServeHTTP(sw, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Tiempo máximo para terminar las peticiones en curso al apagar, según la señal recibida.
+// SIGTERM (Kubernetes, docker stop) espera más que SIGINT (Ctrl-C en local).
+var (
+	shutdownTimeoutTerm = 30 * time.Second // SHUTDOWN_TIMEOUT_TERM
+	shutdownTimeoutInt  = 5 * time.Second  // SHUTDOWN_TIMEOUT_INT
+)
+
+// shutdownTimeout devuelve el tiempo de drenaje configurado para la señal
+func shutdownTimeout(sig os.Signal) time.Duration {
+	if sig == syscall.SIGTERM {
+		return shutdownTimeoutTerm
+	}
+	return shutdownTimeoutInt
+}
+
+// runServer sirve peticiones hasta recibir SIGINT o SIGTERM y entonces deja de aceptar
+// conexiones y espera a que terminen las peticiones en curso, como mucho el tiempo de la señal.
+// Al volver, quien llama puede cerrar la base de datos sabiendo que ya no hay peticiones usándola.
+func runServer(srv *http.Server) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		if tlsEnabled() {
+			serveErr <- srv.ListenAndServeTLS(tlsCertFile, tlsKeyFile)
+			return
+		}
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-serveErr:
+		return err
+	case sig := <-signals:
+		timeout := shutdownTimeout(sig)
+		log.Printf("Señal %s recibida: apagando el servidor (drenaje máximo %s)", sig, timeout)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			return err
+		}
+		if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		log.Println("Servidor detenido: todas las peticiones terminaron")
+		return nil
+	}
+}
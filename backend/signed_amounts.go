@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// Si es true los gastos se guardan en negativo y los ingresos en positivo
+// (STORE_SIGNED_AMOUNTS), de modo que el balance es un simple SUM(amount). La API no cambia:
+// sigue usando importes positivos más el tipo, y la conversión ocurre al leer y al escribir.
+var storeSignedAmounts = false
+
+// magnitudeSQL es la expresión SQL del importe en positivo, sea cual sea el modo de guardado
+func magnitudeSQL() string {
+	if storeSignedAmounts {
+		return "abs(amount)"
+	}
+	return "amount"
+}
+
+// signedSQL es la expresión SQL del importe con signo: ingresos en positivo y gastos en negativo
+func signedSQL() string {
+	if storeSignedAmounts {
+		return "amount"
+	}
+	return "CASE WHEN type = 'income' THEN amount ELSE -amount END"
+}
+
+// storedAmount devuelve el valor que se guarda en la columna amount para la transacción
+func storedAmount(t Transaction) Amount {
+	if storeSignedAmounts && t.Type == "expense" {
+		return t.Amount.Neg()
+	}
+	return t.Amount
+}
+
+// apiAmount devuelve al importe leído de la base de datos la forma de la API (siempre positivo)
+func apiAmount(t *Transaction) {
+	if t.Amount.IsNegative() {
+		t.Amount = t.Amount.Neg()
+	}
+}
+
+// migrateAmountSigns adapta los importes ya guardados al modo configurado: niega los gastos
+// positivos al activar STORE_SIGNED_AMOUNTS y los vuelve a positivo al desactivarlo.
+// Es idempotente, así que se ejecuta en cada arranque; ambas tablas se convierten en una
+// misma transacción y cada conversión queda registrada con el número de filas reescritas.
+func migrateAmountSigns() error {
+	stmt := "UPDATE %s SET amount = abs(amount) WHERE amount < 0"
+	direction := "gastos guardados en positivo"
+	if storeSignedAmounts {
+		stmt = "UPDATE %s SET amount = -amount WHERE type = 'expense' AND amount > 0"
+		direction = "gastos guardados en negativo"
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"transactions", "transactions_archive"} {
+		res, err := tx.Exec(fmt.Sprintf(stmt, table))
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Printf("STORE_SIGNED_AMOUNTS=%t ha cambiado: %d filas de %s reescritas (%s)", storeSignedAmounts, n, table, direction)
+		}
+	}
+	return tx.Commit()
+}
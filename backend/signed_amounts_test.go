@@ -0,0 +1,78 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestStoredAmountModes(t *testing.T) {
+	defer func() { storeSignedAmounts = false }()
+
+	cases := []struct {
+		signed bool
+		typ    string
+		want   string
+	}{
+		{false, "expense", "19.99"},
+		{false, "income", "19.99"},
+		{true, "expense", "-19.99"},
+		{true, "income", "19.99"},
+	}
+	for _, c := range cases {
+		storeSignedAmounts = c.signed
+		tx := Transaction{Amount: mustAmount(t, "19.99"), Type: c.typ}
+		stored := storedAmount(tx)
+		if stored.String() != c.want {
+			t.Errorf("signed=%t %s: se guarda %s, se esperaba %s", c.signed, c.typ, stored, c.want)
+		}
+
+		// Al leer, la API vuelve a ver el importe en positivo
+		read := Transaction{Amount: stored, Type: c.typ}
+		apiAmount(&read)
+		if read.Amount.String() != "19.99" {
+			t.Errorf("signed=%t %s: se lee %s, se esperaba 19.99", c.signed, c.typ, read.Amount)
+		}
+	}
+}
+
+func TestAmountSQLModes(t *testing.T) {
+	defer func() { storeSignedAmounts = false }()
+
+	storeSignedAmounts = false
+	if magnitudeSQL() != "amount" || signedSQL() == "amount" {
+		t.Errorf("sin signo: magnitude=%q signed=%q", magnitudeSQL(), signedSQL())
+	}
+	storeSignedAmounts = true
+	if magnitudeSQL() != "abs(amount)" || signedSQL() != "amount" {
+		t.Errorf("con signo: magnitude=%q signed=%q", magnitudeSQL(), signedSQL())
+	}
+}
+
+func TestMigrateAmountSigns(t *testing.T) {
+	defer func() { storeSignedAmounts = false }()
+
+	cases := []struct {
+		signed bool
+		stmt   string
+	}{
+		{true, "SET amount = -amount WHERE type = 'expense' AND amount > 0"},
+		{false, "SET amount = abs(amount) WHERE amount < 0"},
+	}
+	for _, c := range cases {
+		storeSignedAmounts = c.signed
+		mock := newMockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE transactions " + regexp.QuoteMeta(c.stmt)).WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec("UPDATE transactions_archive " + regexp.QuoteMeta(c.stmt)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		if err := migrateAmountSigns(); err != nil {
+			t.Errorf("signed=%t: %v", c.signed, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("signed=%t: %v", c.signed, err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/shopspring/decimal"
+)
+
+// Indica si la extensión pg_trgm está disponible; sin ella /transaction/{id}/similar responde 503
+var trigramAvailable = false
+
+// Número de resultados por defecto y máximo de /transaction/{id}/similar
+const (
+	defaultSimilarCount = 5
+	maxSimilarCount     = 20
+)
+
+// Similitud de trigramas mínima (0-1) de la descripción para considerar parecidas dos transacciones
+const minDescriptionSimilarity = 0.3
+
+// SimilarTransaction es una transacción parecida con su puntuación (1 = mismo beneficiario
+// o descripción idéntica)
+type SimilarTransaction struct {
+	Transaction Transaction `json:"transaction"`
+	Score       float64     `json:"score"`
+}
+
+// enableTrigram intenta activar pg_trgm. Si el usuario de la base de datos no tiene permiso
+// para crear extensiones se registra y la búsqueda de parecidas queda desactivada.
+func enableTrigram() {
+	if _, err := db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm"); err != nil {
+		log.Printf("pg_trgm no disponible, /transaction/{id}/similar desactivado: %v", err)
+		return
+	}
+	trigramAvailable = true
+}
+
+// Handler para /transaction/{id}/similar (GET: transacciones anteriores parecidas por
+// descripción, con similitud de trigramas, o con el mismo beneficiario; ?limit= hasta 20)
+func getSimilarTransactions(w http.ResponseWriter, r *http.Request, id int) {
+	if !trigramAvailable {
+		writeError(w, r, http.StatusServiceUnavailable, msgSimilarUnavailable)
+		return
+	}
+	limit := defaultSimilarCount
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxSimilarCount {
+			writeError(w, r, http.StatusBadRequest, msgInvalidSimilarLimit, maxSimilarCount)
+			return
+		}
+		limit = n
+	}
+
+	t, err := fetchTransaction(r.Context(), id)
+	if err == sql.ErrNoRows {
+		writeError(w, r, http.StatusNotFound, msgNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := queryReadDB(r.Context(), "similar_transactions", `
+		SELECT `+transactionColumns+`, score FROM (
+			SELECT *, GREATEST(
+				similarity(lower(description), lower($2)),
+				CASE WHEN $3::text IS NOT NULL AND lower(payee) = lower($3) THEN 1 ELSE 0 END
+			) AS score
+			FROM transactions
+			WHERE `+visibleTransactions+` AND id <> $1
+		) AS candidates
+		WHERE score >= $4
+		ORDER BY score DESC, created_at DESC
+		LIMIT $5`, id, t.Description, t.Payee, minDescriptionSimilarity, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	similar := []SimilarTransaction{}
+	for rows.Next() {
+		var s SimilarTransaction
+		if err := rows.Scan(append(transactionScanDest(&s.Transaction), &s.Score)...); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		apiAmount(&s.Transaction)
+		s.Score = roundRatio(decimal.NewFromFloat(s.Score))
+		similar = append(similar, s)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, similar)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Cada cuánto se recalculan las instantáneas mensuales (SUMMARY_SNAPSHOT_INTERVAL).
+// 0 desactiva el job; /summary/monthly?source=snapshot devolverá lo último calculado.
+var summarySnapshotInterval = 15 * time.Minute
+
+// Resúmenes mensuales precalculados para las gráficas históricas
+const createSnapshotsTableSQL = `
+	CREATE TABLE IF NOT EXISTS summary_snapshots (
+		month DATE PRIMARY KEY,
+		income NUMERIC(14, 2) NOT NULL,
+		expense NUMERIC(14, 2) NOT NULL,
+		count INTEGER NOT NULL,
+		computed_at TIMESTAMP WITH TIME ZONE NOT NULL
+	);`
+
+// MonthlySummary son los totales de un mes
+type MonthlySummary struct {
+	Month   string `json:"month"` // YYYY-MM en la zona horaria de la aplicación
+	Income  Amount `json:"income"`
+	Expense Amount `json:"expense"`
+	Net     Amount `json:"net"`
+	Count   int    `json:"count"`
+	// SavingsRate se calcula como en /summary: null si el mes no tiene ingresos
+	SavingsRate *float64 `json:"savings_rate"`
+}
+
+// startSnapshotJob recalcula todas las instantáneas al arrancar y después, en cada tick,
+// las del mes actual y el anterior (los únicos que cambian con el uso normal).
+// Termina cuando se cancela ctx; el canal devuelto se cierra al terminar.
+func startSnapshotJob(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	if summarySnapshotInterval <= 0 || readOnly {
+		close(done)
+		return done
+	}
+	go func() {
+		defer close(done)
+		if err := refreshSnapshots(ctx, time.Time{}); err != nil {
+			log.Printf("Error al calcular las instantáneas de resumen: %v", err)
+		}
+		ticker := time.NewTicker(summarySnapshotInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				now := appNow()
+				previous := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.UTC)
+				if err := refreshSnapshots(ctx, previous); err != nil {
+					log.Printf("Error al actualizar las instantáneas de resumen: %v", err)
+				}
+			}
+		}
+	}()
+	return done
+}
+
+// refreshSnapshots recalcula los meses desde since (todos si es cero) y los guarda con upsert.
+// Los meses del rango se borran antes para que uno que se haya quedado sin transacciones
+// no conserve totales antiguos.
+func refreshSnapshots(ctx context.Context, since time.Time) error {
+	var sinceArg any
+	if !since.IsZero() {
+		sinceArg = since.Format(dateLayout)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := execTx(ctx, tx, "snapshots_clear",
+		"DELETE FROM summary_snapshots WHERE $1::date IS NULL OR month >= $1::date", sinceArg); err != nil {
+		return err
+	}
+	_, err = execTx(ctx, tx, "snapshots_upsert", `
+		INSERT INTO summary_snapshots (month, income, expense, count, computed_at)
+		SELECT date_trunc('month', created_at AT TIME ZONE $1)::date,
+		       COALESCE(SUM(CASE WHEN type = 'income' THEN `+magnitudeSQL()+` END), 0),
+		       COALESCE(SUM(CASE WHEN type = 'expense' THEN `+magnitudeSQL()+` END), 0),
+		       COUNT(*), now()
+		FROM transactions
+		WHERE `+visibleTransactions+` AND ($2::date IS NULL OR created_at AT TIME ZONE $1 >= $2::date)
+		GROUP BY 1
+		ON CONFLICT (month) DO UPDATE SET income = EXCLUDED.income, expense = EXCLUDED.expense,
+			count = EXCLUDED.count, computed_at = EXCLUDED.computed_at`,
+		appLocation.String(), sinceArg)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Handler para /summary/monthly (GET: totales por mes). Por defecto se calculan al momento
+// con los filtros de la lista; con ?source=snapshot se leen de summary_snapshots, que es más
+// rápido pero puede ir hasta SUMMARY_SNAPSHOT_INTERVAL por detrás y solo admite from/to.
+// Con ?convert_to=EUR (solo al momento) los importes se convierten con los tipos de FX_RATES.
+func getMonthlySummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r, "GET")
+		return
+	}
+
+	query := r.URL.Query()
+	_, rate, err := parseConvertTo(r)
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return
+	}
+	var sqlText string
+	var args []any
+	switch query.Get("source") {
+	case "", "live":
+		filter, err := buildTransactionFilter(query)
+		if err != nil {
+			http.Error(w, errorText(r, err), http.StatusBadRequest)
+			return
+		}
+		amount := convertedAmountSQL(filter, rate)
+		sqlText = `
+			SELECT date_trunc('month', created_at AT TIME ZONE ` + filter.arg(appLocation.String()) + `)::date AS month,
+			       COALESCE(SUM(CASE WHEN type = 'income' THEN ` + amount + ` END), 0),
+			       COALESCE(SUM(CASE WHEN type = 'expense' THEN ` + amount + ` END), 0),
+			       COUNT(*)
+			FROM transactions` + filter.where() + `
+			GROUP BY 1 ORDER BY 1`
+		args = filter.args
+	case "snapshot":
+		if query.Has("convert_to") {
+			writeError(w, r, http.StatusBadRequest, msgConvertToSnapshot)
+			return
+		}
+		from, err := parseDateParam(query, "from")
+		if err != nil {
+			http.Error(w, errorText(r, err), http.StatusBadRequest)
+			return
+		}
+		to, err := parseDateParam(query, "to")
+		if err != nil {
+			http.Error(w, errorText(r, err), http.StatusBadRequest)
+			return
+		}
+		f := &sqlFilter{}
+		if !from.IsZero() {
+			f.add("month >= date_trunc('month', " + f.arg(from.Format(dateLayout)) + "::date)")
+		}
+		if !to.IsZero() {
+			f.add("month <= " + f.arg(to.Format(dateLayout)) + "::date")
+		}
+		sqlText = "SELECT month, income, expense, count FROM summary_snapshots" + f.where() + " ORDER BY month"
+		args = f.args
+	default:
+		writeError(w, r, http.StatusBadRequest, msgInvalidSummarySource)
+		return
+	}
+
+	rows, err := queryReadDB(r.Context(), "summary_monthly", sqlText, args...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	months := []MonthlySummary{}
+	for rows.Next() {
+		var m MonthlySummary
+		var month time.Time
+		if err := rows.Scan(&month, &m.Income, &m.Expense, &m.Count); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		m.Month = month.Format("2006-01")
+		m.Net = m.Income.Sub(m.Expense)
+		m.SavingsRate = savingsRate(m.Income, m.Expense)
+		months = append(months, m)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, months)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+)
+
+// Mínimo de partes de una división
+const minSplitParts = 2
+
+// SplitPart es una de las partes en que se divide una transacción.
+// Si no se indica descripción se hereda la de la transacción original.
+type SplitPart struct {
+	Amount      Amount `json:"amount"`
+	Description string `json:"description"`
+}
+
+// Handler para /transaction/{id}/split (POST: dividir una transacción en partes).
+// Las partes deben sumar exactamente (al céntimo) el importe original. Se crean como
+// transacciones hijas con parent_id = id, heredando tipo, fecha, origen, conciliación, beneficiario
+// y estado (una parte de un cargo pendiente también está pendiente),
+// y la original se marca como dividida para que deje de contar en listas y resúmenes.
+// Todo ocurre en una única transacción de base de datos.
+func splitTransaction(w http.ResponseWriter, r *http.Request, id int) {
+	var parts []SplitPart
+	if !decodeJSON(w, r, maxBodyBytes, &parts) {
+		return
+	}
+	if len(parts) < minSplitParts {
+		writeError(w, r, http.StatusBadRequest, msgSplitTooFewParts, minSplitParts)
+		return
+	}
+	var sum Amount
+	for i, p := range parts {
+		// Cada parte se guarda en NUMERIC(10, 2): con más decimales se redondearía al guardarla
+		// y las partes ya no sumarían el original aunque la suma sin redondear sí lo haga
+		if !p.Amount.IsPositive() || !p.Amount.Equal(p.Amount.Round(2)) {
+			writeError(w, r, http.StatusBadRequest, msgSplitInvalidPart, i+1)
+			return
+		}
+		sum = sum.Add(p.Amount)
+	}
+
+	ctx := r.Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var parent Transaction
+	err = scanTransaction(queryRowTx(ctx, tx, "split_lock_parent",
+		"SELECT "+transactionColumns+" FROM transactions WHERE id = $1 FOR UPDATE", id), &parent)
+	if err == sql.ErrNoRows {
+		writeError(w, r, http.StatusNotFound, msgNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if parent.IsTemplate {
+		writeError(w, r, http.StatusConflict, msgTemplateSplit)
+		return
+	}
+	if parent.Split {
+		writeError(w, r, http.StatusConflict, msgAlreadySplit)
+		return
+	}
+	// La columna guarda céntimos: la suma se compara redondeada a dos decimales
+	if !sum.Round(2).Equal(parent.Amount.Decimal) {
+		writeError(w, r, http.StatusBadRequest, msgSplitSumMismatch, sum, parent.Amount)
+		return
+	}
+
+	children := make([]Transaction, 0, len(parts))
+	for _, p := range parts {
+		child := Transaction{
+			Description: p.Description,
+			Amount:      p.Amount,
+			Type:        parent.Type,
+			CreatedAt:   parent.CreatedAt,
+			Source:      parent.Source,
+			Cleared:     parent.Cleared,
+			ParentID:    &parent.ID,
+			Payee:       parent.Payee,
+			Status:      parent.Status,
+		}
+		if child.Description == "" {
+			child.Description = parent.Description
+		}
+		err := queryRowTx(ctx, tx, "split_insert_part",
+			"INSERT INTO transactions(description, amount, type, created_at, source, cleared, parent_id, payee, status) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, updated_at",
+			child.Description, storedAmount(child), child.Type, child.CreatedAt, child.Source, child.Cleared, parent.ID, child.Payee, child.Status).Scan(&child.ID, &child.UpdatedAt)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		children = append(children, child)
+	}
+
+	if _, err := execTx(ctx, tx, "split_mark_parent", "UPDATE transactions SET is_split = true, updated_at = CURRENT_TIMESTAMP WHERE id = $1", id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, children)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSplitCopiesParentStatus(t *testing.T) {
+	for _, status := range []string{statusPosted, statusPending} {
+		t.Run(status, func(t *testing.T) {
+			parent := transactionRowValues(7, "Supermercado", "30.00", "expense")
+			parent[12] = status
+
+			mock := newMockDB(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT .+ FROM transactions WHERE id = \\$1 FOR UPDATE").
+				WithArgs(7).
+				WillReturnRows(transactionRows().AddRow(parent...))
+			for i, desc := range []string{"Comida", "Limpieza"} {
+				mock.ExpectQuery("INSERT INTO transactions").
+					WithArgs(desc, sqlmock.AnyArg(), "expense", sqlmock.AnyArg(), defaultSource, false, 7, nil, status).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "updated_at"}).AddRow(8+i, time.Now()))
+			}
+			mock.ExpectExec("UPDATE transactions SET is_split = true").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			body := `[{"amount":20,"description":"Comida"},{"amount":10,"description":"Limpieza"}]`
+			rec := serve(handleTransactionByID, "POST", "/transaction/7/split", body)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+			}
+			var parts []Transaction
+			if err := json.Unmarshal(rec.Body.Bytes(), &parts); err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range parts {
+				if p.Status != status {
+					t.Errorf("parte %d con estado %q, se esperaba %q", p.ID, p.Status, status)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitRejectsSubCentParts(t *testing.T) {
+	newMockDB(t) // se rechaza antes de abrir la transacción de base de datos
+
+	// 5.005 + 4.995 suman 10.00, pero guardadas serían 5.01 + 5.00 = 10.01
+	rec := serve(handleTransactionByID, "POST", "/transaction/7/split", `[{"amount":"5.005"},{"amount":"4.995"}]`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("estado %d, se esperaba 400: %s", rec.Code, rec.Body)
+	}
+	if want := localize(httptest.NewRequest("POST", "/", nil), msgSplitInvalidPart, 1); !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("respuesta %q, se esperaba %q", rec.Body, want)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+)
+
+// Estados de una transacción: las de tarjeta quedan pendientes hasta que el banco las asienta
+const (
+	statusPending = "pending"
+	statusPosted  = "posted"
+)
+
+// isValidStatus indica si el estado es uno de los admitidos
+func isValidStatus(s string) bool {
+	return s == statusPending || s == statusPosted
+}
+
+// Handler para /transaction/{id}/status (PATCH: cambiar entre pending y posted)
+func setTransactionStatus(w http.ResponseWriter, r *http.Request, id int) {
+	var body struct {
+		Status string `json:"status"`
+	}
+	if !decodeJSON(w, r, maxBodyBytes, &body) {
+		return
+	}
+	if !isValidStatus(body.Status) {
+		writeError(w, r, http.StatusBadRequest, msgInvalidStatus)
+		return
+	}
+
+	var t Transaction
+	err := scanTransaction(queryRowDB(r.Context(), "set_transaction_status",
+		"UPDATE transactions SET status=$1, updated_at=CURRENT_TIMESTAMP WHERE id=$2 RETURNING "+transactionColumns, body.Status, id), &t)
+	if err == sql.ErrNoRows {
+		writeError(w, r, http.StatusNotFound, msgNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, t)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+)
+
+// Summary agrega los totales de las transacciones que cumplen el filtro
+type Summary struct {
+	Income  Amount `json:"income"`
+	Expense Amount `json:"expense"`
+	Balance Amount `json:"balance"`
+	Count   int    `json:"count"`
+	// Currency es la moneda de los importes: DEFAULT_CURRENCY o la pedida con ?convert_to=
+	Currency string `json:"currency"`
+	// SavingsRate es (ingresos-gastos)/ingresos. Es null si no hay ingresos y
+	// puede ser negativa cuando los gastos superan a los ingresos (no se recorta).
+	SavingsRate *float64 `json:"savings_rate"`
+}
+
+// savingsRate calcula la tasa de ahorro, o nil si los ingresos son 0
+func savingsRate(income, expense Amount) *float64 {
+	if income.IsZero() {
+		return nil
+	}
+	rate := roundRatio(income.Sub(expense).Div(income.Decimal))
+	return &rate
+}
+
+// Handler para /summary (GET: totales de ingresos, gastos y balance). Con ?convert_to=EUR
+// los importes se convierten a esa moneda con los tipos de FX_RATES.
+func getSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r, "GET")
+		return
+	}
+
+	filter, err := buildTransactionFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return
+	}
+	currency, rate, err := parseConvertTo(r)
+	if err != nil {
+		http.Error(w, errorText(r, err), http.StatusBadRequest)
+		return
+	}
+
+	var key string
+	var generation uint64
+	if summaryCacheTTL > 0 {
+		key = summaryCacheKey(r.URL.Query())
+		cached, ok, gen := cachedSummary(key)
+		if ok {
+			writeJSON(w, r, http.StatusOK, cached)
+			return
+		}
+		generation = gen
+	}
+
+	s := Summary{Currency: currency}
+	amount := convertedAmountSQL(filter, rate)
+	err = queryRowReadDB(r.Context(), "summary", `
+		SELECT COALESCE(SUM(CASE WHEN type = 'income' THEN `+amount+` END), 0),
+		       COALESCE(SUM(CASE WHEN type = 'expense' THEN `+amount+` END), 0),
+		       COUNT(*)
+		FROM transactions`+filter.where(), filter.args...).Scan(&s.Income, &s.Expense, &s.Count)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.Balance = s.Income.Sub(s.Expense)
+	s.SavingsRate = savingsRate(s.Income, s.Expense)
+	if summaryCacheTTL > 0 {
+		storeSummary(key, s, generation)
+	}
+
+	writeJSON(w, r, http.StatusOK, s)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Tiempo que se reutiliza un resumen calculado (SUMMARY_CACHE_TTL, p. ej. "10s").
+// 0, el valor por defecto, desactiva la caché.
+var summaryCacheTTL time.Duration
+
+// summaryCache guarda los resultados de /summary por combinación de filtros.
+// generation aumenta con cada escritura: un cálculo que empezó antes de una escritura
+// no se guarda, aunque termine después de la invalidación.
+var summaryCache = struct {
+	sync.Mutex
+	entries    map[string]summaryCacheEntry
+	generation uint64
+}{entries: map[string]summaryCacheEntry{}}
+
+type summaryCacheEntry struct {
+	summary Summary
+	expires time.Time
+}
+
+// summaryCacheKey normaliza los parámetros de la petición (Encode los ordena) sin los de formato
+func summaryCacheKey(q url.Values) string {
+	q = cloneValues(q)
+	q.Del("pretty")
+	return q.Encode()
+}
+
+func cloneValues(q url.Values) url.Values {
+	c := make(url.Values, len(q))
+	for k, v := range q {
+		c[k] = append([]string(nil), v...)
+	}
+	return c
+}
+
+// cachedSummary devuelve el resumen guardado para key si sigue vigente, y la generación
+// actual para pasarla después a storeSummary
+func cachedSummary(key string) (Summary, bool, uint64) {
+	summaryCache.Lock()
+	defer summaryCache.Unlock()
+	e, ok := summaryCache.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		return Summary{}, false, summaryCache.generation
+	}
+	return e.summary, true, summaryCache.generation
+}
+
+// storeSummary guarda el resumen salvo que haya habido escrituras desde que se empezó a calcular
+func storeSummary(key string, s Summary, generation uint64) {
+	summaryCache.Lock()
+	defer summaryCache.Unlock()
+	if generation != summaryCache.generation {
+		return
+	}
+	summaryCache.entries[key] = summaryCacheEntry{summary: s, expires: time.Now().Add(summaryCacheTTL)}
+}
+
+// invalidateSummaryCache descarta todos los resúmenes guardados
+func invalidateSummaryCache() {
+	summaryCache.Lock()
+	defer summaryCache.Unlock()
+	summaryCache.generation++
+	clear(summaryCache.entries)
+}
+
+// summaryCacheHandler invalida la caché de resúmenes al terminar cualquier escritura
+// (crear, actualizar, borrar, dividir, archivar...), de modo que nunca se sirvan totales obsoletos
+func summaryCacheHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+		if summaryCacheTTL > 0 && isWriteMethod(r.Method) {
+			invalidateSummaryCache()
+		}
+	})
+}
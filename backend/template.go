@@ -0,0 +1,75 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+)
+
+// Handler para /templates (GET: transacciones marcadas como plantilla, por descripción)
+func getTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r, "GET")
+		return
+	}
+
+	rows, err := queryReadDB(r.Context(), "list_templates",
+		"SELECT "+transactionColumns+" FROM transactions WHERE is_template ORDER BY description, id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	templates := []Transaction{}
+	for rows.Next() {
+		var t Transaction
+		if err := scanTransaction(rows, &t); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		templates = append(templates, t)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, templates)
+}
+
+// Handler para /transaction/{id}/use-template (POST: crea una transacción real a partir
+// de la plantilla, con la fecha actual, sin conciliar y asentada)
+func useTemplate(w http.ResponseWriter, r *http.Request, id int) {
+	tmpl, err := fetchTransaction(r.Context(), id)
+	if err == sql.ErrNoRows {
+		writeError(w, r, http.StatusNotFound, msgNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !tmpl.IsTemplate {
+		writeError(w, r, http.StatusConflict, msgNotTemplate, id)
+		return
+	}
+
+	t := Transaction{
+		Description: tmpl.Description,
+		Amount:      tmpl.Amount,
+		Type:        tmpl.Type,
+		Source:      tmpl.Source,
+		Payee:       tmpl.Payee,
+		CreatedAt:   appNow(),
+		Status:      statusPosted,
+	}
+	err = queryRowDB(r.Context(), "use_template",
+		"INSERT INTO transactions(description, amount, type, source, payee, created_at, status) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at",
+		t.Description, storedAmount(t), t.Type, t.Source, t.Payee, t.CreatedAt.UTC(), t.Status).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, t)
+}
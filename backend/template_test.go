@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUseTemplateStatus(t *testing.T) {
+	tmpl := transactionRowValues(3, "Alquiler", "800.00", "expense")
+	tmpl[10] = true // is_template
+
+	mock := newMockDB(t)
+	mock.ExpectQuery("SELECT .+ FROM transactions WHERE id = \\$1").
+		WithArgs(3).
+		WillReturnRows(transactionRows().AddRow(tmpl...))
+	mock.ExpectQuery("INSERT INTO transactions").
+		WithArgs("Alquiler", sqlmock.AnyArg(), "expense", defaultSource, nil, sqlmock.AnyArg(), statusPosted).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(4, time.Now(), time.Now()))
+
+	rec := serve(handleTransactionByID, "POST", "/transaction/3/use-template", "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("estado %d: %s", rec.Code, rec.Body)
+	}
+	var got Transaction
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != statusPosted {
+		t.Errorf("status %q en la respuesta, se esperaba %q", got.Status, statusPosted)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/url"
+	"time"
+)
+
+// Zona horaria de la aplicación (APP_TIMEZONE, nombre IANA como "Europe/Madrid"); UTC por defecto
+var appLocation = time.UTC
+
+// parseTruncate valida ?truncate=minute|hour|day; "" significa sin truncar
+func parseTruncate(q url.Values) (string, error) {
+	switch unit := q.Get("truncate"); unit {
+	case "", "minute", "hour", "day":
+		return unit, nil
+	default:
+		return "", newAPIError(msgInvalidTruncate)
+	}
+}
+
+// truncateTime trunca t a la unidad indicada en la zona horaria de la aplicación.
+// Solo afecta a la respuesta; lo almacenado no cambia.
+func truncateTime(t time.Time, unit string) time.Time {
+	local := t.In(appLocation)
+	switch unit {
+	case "minute":
+		return local.Truncate(time.Minute)
+	case "hour":
+		// Truncate opera sobre el instante absoluto; se recompone la hora local
+		// para que funcione también en zonas con desfase no entero
+		return time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, appLocation)
+	case "day":
+		return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, appLocation)
+	}
+	return t
+}
+
+// truncateTimestamps aplica ?truncate= a las marcas de tiempo de la transacción
+func truncateTimestamps(t *Transaction, unit string) {
+	if unit == "" {
+		return
+	}
+	t.CreatedAt = truncateTime(t.CreatedAt, unit)
+}
+
+// appNow es la hora actual en la zona horaria de la aplicación. Las creaciones la usan como
+// created_at para que la fecha no dependa del reloj ni de la zona del servidor de base de datos.
+func appNow() time.Time {
+	return time.Now().In(appLocation)
+}
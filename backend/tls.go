@@ -0,0 +1,64 @@
+package main
+
+import (
+	"crypto/tls"
+	"log"
+	"os"
+	"strings"
+)
+
+// Certificado y clave para servir HTTPS (TLS_CERT_FILE, TLS_KEY_FILE). Sin ellos se sirve HTTP.
+var tlsCertFile, tlsKeyFile string
+
+// Versión mínima de TLS aceptada (TLS_MIN_VERSION=1.2|1.3, por defecto 1.2)
+var tlsMinVersion uint16 = tls.VersionTLS12
+
+// Suites de cifrado permitidas en TLS 1.2 (TLS_CIPHER_SUITES, nombres separados por comas).
+// nil deja la lista segura por defecto de Go. TLS 1.3 no permite elegirlas.
+var tlsCipherSuites []uint16
+
+var tlsVersionNames = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// loadTLSConfig lee la configuración TLS del entorno. Solo se admiten suites de
+// tls.CipherSuites(): las de tls.InsecureCipherSuites() se rechazan al arrancar.
+func loadTLSConfig() {
+	tlsCertFile = os.Getenv("TLS_CERT_FILE")
+	tlsKeyFile = os.Getenv("TLS_KEY_FILE")
+	if (tlsCertFile == "") != (tlsKeyFile == "") {
+		log.Fatalf("TLS_CERT_FILE y TLS_KEY_FILE deben indicarse juntos")
+	}
+	if v := os.Getenv("TLS_MIN_VERSION"); v != "" {
+		version, ok := tlsVersionNames[v]
+		if !ok {
+			log.Fatalf("TLS_MIN_VERSION inválido: %q (usa 1.2 o 1.3)", v)
+		}
+		tlsMinVersion = version
+	}
+	secure := map[string]uint16{}
+	for _, suite := range tls.CipherSuites() {
+		secure[suite.Name] = suite.ID
+	}
+	for _, name := range splitList(os.Getenv("TLS_CIPHER_SUITES")) {
+		id, ok := secure[strings.ToUpper(name)]
+		if !ok {
+			log.Fatalf("TLS_CIPHER_SUITES: suite desconocida o insegura %q", name)
+		}
+		tlsCipherSuites = append(tlsCipherSuites, id)
+	}
+}
+
+// tlsEnabled indica si el servidor debe servir HTTPS
+func tlsEnabled() bool {
+	return tlsCertFile != ""
+}
+
+// serverTLSConfig construye la configuración TLS del servidor
+func serverTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:   tlsMinVersion,
+		CipherSuites: tlsCipherSuites,
+	}
+}
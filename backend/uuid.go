@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// Forma canónica de un UUID: 8-4-4-4-12 dígitos hexadecimales
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// normalizeUUID pasa a minúsculas y sin espacios el uuid que envía el cliente e indica si es válido
+func normalizeUUID(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return s, uuidPattern.MatchString(s)
+}
+
+// fetchTransactionByUUID lee una transacción por su uuid; devuelve sql.ErrNoRows si no existe
+func fetchTransactionByUUID(ctx context.Context, uuid string) (Transaction, error) {
+	var t Transaction
+	err := scanTransaction(queryRowDB(ctx, "get_transaction_by_uuid",
+		"SELECT "+transactionColumns+" FROM transactions WHERE uuid = $1", uuid), &t)
+	return t, err
+}